@@ -0,0 +1,1756 @@
+package allocations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// define the datatypes needed, namely people and tables
+
+// Person is someone to be seated, along with the names of the people they would like to sit with
+type Person struct {
+	Name        string            `json:"name"` // must be unique
+	Preferences []Preference      `json:"preferences"`
+	Avoid       []string          `json:"avoid"`      // people this person must not be sat with
+	Tags        []string          `json:"tags"`       // groups this person belongs to, which others can prefer with "#tag"
+	VIP         float64           `json:"vip"`        // how important the person is, for sitting them at the more desirable tables
+	Attributes  map[string]string `json:"attributes"` // e.g. department or team, where tables can be balanced by one (see Config.Balance)
+	empty       bool              // a placeholder for an empty seat, which can be swapped around like a person
+	id          int               // the person's index in the problem, which is used in place of their name when scoring
+	avoidIDs    []int             // the indexes of the people in Avoid
+	tagIDs      []int             // the indexes of the tags in Tags
+}
+
+// Preference is someone a person would like to sit with, weighted by how much it matters to them - or, if the name
+// starts with "#", anyone with that tag (where each of them sat with the person counts)
+type Preference struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	id     int     // the index of the named person (or tag), or -1 if there is nobody with that name
+	isTag  bool
+}
+
+// UnmarshalJSON accepts either a bare name, which is given a weight of 1, or an object with a name and weight (where
+// the weight also defaults to 1)
+func (p *Preference) UnmarshalJSON(data []byte) error {
+	return p.unmarshal(data, false)
+}
+
+// unmarshal reads the preference as UnmarshalJSON does, only allowing known fields in an object if strict
+func (p *Preference) unmarshal(data []byte, strict bool) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		*p = Preference{Name: name, Weight: 1}
+		return nil
+	}
+
+	// a type without this method, so that unmarshalling it doesn't recurse
+	type weightedPreference Preference
+	preference := weightedPreference{Weight: 1}
+	err := decodeJSON(data, &preference, strict)
+	if err != nil {
+		return err
+	}
+	*p = Preference(preference)
+	return nil
+}
+
+type table struct {
+	name     string // the table's name, or "Table N" if it wasn't given one
+	capacity int
+	minimum  int // the fewest people the table can seat, with the seats beyond them left empty
+	people   []Person
+	seated   []bool // whether each person, by their index, is sat at this table (empty seats never are)
+	tagged   []int  // the number of people with each tag, by its index, sat at this table
+	adjacent []int  // indexes of the tables next to this one
+	pinned   int    // the number of seats at the front of people taken by people pinned to this table
+	anchored bool   // whether the table's index matters beyond its size (it's named, or forbid or previous refer to it)
+
+	desirability float64 // how good a table it is to be sat at, for the VIPs
+}
+
+// has reports whether the person with the given index is sat at the table, where -1 (nobody) never is
+func (t table) has(id int) bool {
+	return id >= 0 && t.seated[id]
+}
+
+// matches returns how many people sat at the table (besides the person themselves) the preference is for
+func (t table) matches(preference Preference, self Person) int {
+	if !preference.isTag {
+		if t.has(preference.id) {
+			return 1
+		}
+		return 0
+	}
+	if preference.id < 0 {
+		return 0
+	}
+	matches := t.tagged[preference.id]
+	for _, tag := range self.tagIDs {
+		if tag == preference.id && t.has(self.id) {
+			matches--
+		}
+	}
+	return matches
+}
+
+// seat marks the person as sat at the table
+func (t table) seat(p Person) {
+	t.seated[p.id] = true
+	for _, tag := range p.tagIDs {
+		t.tagged[tag]++
+	}
+}
+
+// unseat marks the person as no longer sat at the table
+func (t table) unseat(p Person) {
+	t.seated[p.id] = false
+	for _, tag := range p.tagIDs {
+		t.tagged[tag]--
+	}
+}
+
+// PlusOne is a pair of people who must be sat at the same table
+type PlusOne struct {
+	PersonOne string `json:"personOne"`
+	PersonTwo string `json:"personTwo"`
+}
+
+// scoring holds everything the cost functions need besides the assignment itself
+type scoring struct {
+	plusOnes       map[int]int   // by index, where a plus-one of -1 is nobody in the problem
+	preferences    float64       // each satisfied preference's weight is multiplied by this (0 is taken as 1)
+	adjacentCredit float64       // the credit given for a preference sat at an adjacent table
+	minSatisfied   int           // everyone should be sat with at least this many of their preferences
+	avoidPenalty   float64       // the cost taken off for each person sat with someone they want to avoid
+	lonelyPenalty  float64       // the cost taken off for each person sat with none of their preferences
+	met            [][]int       // how many times each pair has been sat together in earlier rounds, by index (if any)
+	metPenalty     float64       // the cost taken off for each of those times, for each pair sat together again
+	mutualBonus    float64       // the extra cost given for each pair sat together who both prefer each other
+	desirability   float64       // the weight given to each person's VIP score times the desirability of their table
+	mutual         map[int][]int // for each person, the people whose preference for them is reciprocated, by index
+	apart          map[int][]int // for each person, the people they must not be sat with, by index
+	together       map[int][]int // for each person, the rest of their group who they must be sat with, by index
+	forbidden      map[int][]int // for each person, the indexes of the tables they must not be sat at
+	previous       map[int]int   // for each person in the previous assignment, the index of the table they were sat at
+	stability      float64       // the cost taken off for each person sat at a different table from before
+	balanced       []int         // for each person by index, their value of the attribute being balanced (-1 if they have none)
+	shares         []float64     // the fraction of the people with the attribute who have each value
+	balance        float64       // the cost taken off for each person a table is away from everyone's mix of the attribute
+	penalty        float64       // the cost taken off for each hard constraint broken
+	worstOffScale  float64       // what each preference satisfied for the worst-off person is worth, under maximin
+}
+
+// Stats records how a run went, to help with tuning the annealing parameters
+type Stats struct {
+	BaseTemperature float64   `json:"baseTemperature"` // the temperature the coldest annealer started at
+	InitialCost     float64   `json:"initialCost"`
+	FinalCost       float64   `json:"finalCost"`
+	MaxPossibleCost float64   `json:"maxPossibleCost"` // the cost if every preference were satisfied
+	Steps           int       `json:"steps"`           // the number of temperature steps
+	Evaluations     int       `json:"evaluations"`     // the number of times the cost function was called
+	AcceptanceRatio float64   `json:"acceptanceRatio"` // the fraction of neighbouring solutions that were moved to
+	ElapsedSeconds  float64   `json:"elapsedSeconds"`
+	Reheats         int       `json:"reheats"`      // the number of times the temperature was raised after stalling
+	Restart         int       `json:"restart"`      // the restart (counting from 0) that found the solution
+	RestartCosts    []float64 `json:"restartCosts"` // the final cost of each restart, in order
+	Rediscovered    []int     `json:"rediscovered"` // the restarts that found the same seating as an earlier restart
+	// for each annealer, the number of times it passed its solution down to the next coldest annealer (which is always a
+	// better one, unless exchanging with the Metropolis criterion)
+	Exchanges []int `json:"exchanges"`
+}
+
+// Progress is how a run is going, reported after each temperature step
+type Progress struct {
+	Step        int
+	Temperature float64 // the temperature of the coldest annealer during the step
+	InitialCost float64 // the cost of the random solution the run started from
+	BestCost    float64 // the best cost any annealer has had so far
+	ColdestCost float64 // the cost of the coldest annealer's solution after the step
+	Elapsed     time.Duration
+	Accepted    int // the number of neighbouring solutions moved to during the step, across all annealers
+	Rejected    int // the number of neighbouring solutions not moved to during the step, across all annealers
+}
+
+// the main annealing function - the seed drives the initial shuffle and then seeds each annealer's own rng, so that a
+// fixed seed gives a fixed result however the goroutines are scheduled (and runs with different seeds don't share
+// rngs). If the context is cancelled, the best solution so far is returned along with the context's error. If the
+// objective can be given from its parts, the cost is updated a table at a time. If a warm start is given, it's used as
+// the initial solution rather than seating the people afresh. Each annealer's solution after each step is offered to the
+// pool, if there is one
+func anneal(ctx context.Context, seed int64, people []Person, tables []table, warmStart []table, s scoring, o objective, cfg AnnealConfig, pool *solutionPool) (result []table, runStats Stats, err error) {
+	costFunction := o.cost
+	concurrentAnnealerCount := cfg.ConcurrentAnnealers
+	start := time.Now()
+
+	// with a time limit, the run stops once it's up rather than once it has cooled - it's not an error, unlike the
+	// caller's context running out
+	runCtx := ctx
+	if cfg.MaxTime > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, cfg.MaxTime)
+		defer cancel()
+	}
+
+	seeder := rand.New(rand.NewSource(seed))
+	var initialSolution []table
+	switch {
+	case warmStart != nil:
+		initialSolution = copyAssignment(warmStart)
+	case cfg.Initialisation == GreedyInit:
+		initialSolution = greedyInitialisation(seeder, people, tables, s, costFunction)
+	case cfg.Initialisation == ClusteredInit:
+		initialSolution = clusteredInitialisation(seeder, people, tables, s.forbidden)
+	default:
+		initialSolution = randomInitialisation(seeder, people, tables, s.forbidden)
+	}
+	movable := movableTables(tables)
+
+	// people can only be moved into empty seats if there are any
+	moveChance := 0.0
+	if hasEmptySeat(initialSolution) {
+		moveChance = cfg.NeighbourMix
+	}
+
+	// the base temperature can be estimated from the initial solution, as long as it leaves something to cool
+	if cfg.TargetAcceptance > 0 {
+		estimate, evaluations := estimateTemperature(seeder, initialSolution, movable, s, costFunction, cfg.SwapCount, moveChance, cfg.TargetAcceptance)
+		if estimate > cfg.FinalTemperature {
+			cfg.BaseTemperature = estimate
+		}
+		runStats.Evaluations += evaluations
+	}
+	baseTemperature := cfg.BaseTemperature
+	runStats.BaseTemperature = baseTemperature
+
+	// each concurrent annealer of differing temperature writes to its own index of these
+	annealerSolutions := make([][]table, concurrentAnnealerCount)
+	annealerCosts := make([]float64, concurrentAnnealerCount)
+	annealerAccepted := make([]int, concurrentAnnealerCount)
+	annealerRejected := make([]int, concurrentAnnealerCount)
+	accepted, rejected := 0, 0
+	annealerRngs := make([]*rand.Rand, concurrentAnnealerCount)
+	runStats.Exchanges = make([]int, concurrentAnnealerCount)
+
+	for i := 0; i < concurrentAnnealerCount; i++ {
+		annealerSolutions[i] = copyAssignment(initialSolution)
+		annealerCosts[i] = costFunction(initialSolution, s)
+		annealerRngs[i] = rand.New(rand.NewSource(seeder.Int63()))
+	}
+	exchangeRng := rand.New(rand.NewSource(seeder.Int63()))
+	runStats.InitialCost = annealerCosts[0]
+	runStats.Evaluations += concurrentAnnealerCount
+
+	// annealing can move to worse solutions, so keep hold of the best any annealer has had
+	bestSolution := copyAssignment(initialSolution)
+	bestCost := annealerCosts[0]
+	stalledSteps := 0
+	reheats := 0
+
+	// with candidate workers, each iteration looks at several neighbours, made by each annealer's own workers for the
+	// whole run
+	candidatesPerIteration := 1
+	var annealerWorkers []*candidateWorkers
+	if cfg.CandidateWorkers > 1 {
+		candidatesPerIteration = cfg.CandidateWorkers
+		annealerWorkers = make([]*candidateWorkers, concurrentAnnealerCount)
+		for i := range annealerWorkers {
+			annealerWorkers[i] = newCandidateWorkers(cfg.CandidateWorkers)
+			defer annealerWorkers[i].stop()
+		}
+	}
+
+	// with adaptive cooling, the cooling rate changes with how quickly the fraction of moves accepted is dropping
+	coolingCfg := cfg
+	lastAcceptance := 0.0
+
+	// while we haven't hit the final temperature (or been cancelled)
+	for baseTemperature > cfg.FinalTemperature && runCtx.Err() == nil {
+
+		// run all of the annealers at once, and wait for them all to finish before exchanging solutions
+		var wg sync.WaitGroup
+		for i := 0; i < concurrentAnnealerCount; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				temperature := baseTemperature * math.Pow(2, float64(i))
+				if annealerWorkers != nil {
+					annealerSolutions[i], annealerCosts[i], annealerAccepted[i], annealerRejected[i] = annealerCandidateIterator(runCtx, annealerRngs[i], annealerSolutions[i], movable, s, o, temperature, cfg.InternalIterations, swapsAt(temperature, cfg), moveChance, annealerWorkers[i])
+					return
+				}
+				annealerSolutions[i], annealerCosts[i], annealerAccepted[i], annealerRejected[i] = annealerInternalIterator(runCtx, annealerRngs[i], annealerSolutions[i], movable, s, o, temperature, cfg.InternalIterations, swapsAt(temperature, cfg), moveChance)
+			}(i)
+		}
+		wg.Wait()
+		improved := false
+		stepAccepted, stepRejected := 0, 0
+		for i := 0; i < concurrentAnnealerCount; i++ {
+			stepAccepted += annealerAccepted[i]
+			stepRejected += annealerRejected[i]
+			pool.offer(annealerSolutions[i], annealerCosts[i])
+			if annealerCosts[i] > bestCost {
+				bestSolution = copyAssignment(annealerSolutions[i])
+				bestCost = annealerCosts[i]
+				improved = true
+			}
+		}
+		accepted += stepAccepted
+		rejected += stepRejected
+		runStats.Steps++
+		runStats.Evaluations += concurrentAnnealerCount * (cfg.InternalIterations*candidatesPerIteration + 1)
+
+		// If a hotter goroutine has a better solution than a colder one then we swap the solutions (or, with the
+		// Metropolis criterion, maybe swap a worse one)
+		for i := concurrentAnnealerCount - 1; i > 0; i-- {
+			if shouldExchange(exchangeRng, cfg.Exchange, annealerCosts[i], annealerCosts[i-1], baseTemperature*math.Pow(2, float64(i)), baseTemperature*math.Pow(2, float64(i-1))) {
+				annealerSolutions[i], annealerSolutions[i-1] = annealerSolutions[i-1], annealerSolutions[i]
+				annealerCosts[i], annealerCosts[i-1] = annealerCosts[i-1], annealerCosts[i]
+				runStats.Exchanges[i]++
+			}
+		}
+
+		if improved {
+			stalledSteps = 0
+		} else {
+			stalledSteps++
+		}
+
+		if cfg.Progress != nil {
+			cfg.Progress(Progress{Step: runStats.Steps, Temperature: baseTemperature, InitialCost: runStats.InitialCost, BestCost: bestCost, ColdestCost: annealerCosts[0], Elapsed: time.Since(start), Accepted: stepAccepted, Rejected: stepRejected})
+		}
+
+		// raise the temperature again if the best cost has stalled, to escape local optima (no hotter than the start)
+		if cfg.ReheatAfterStall > 0 && stalledSteps >= cfg.ReheatAfterStall && reheats < cfg.MaxReheats {
+			baseTemperature = math.Min(baseTemperature*cfg.ReheatFactor, cfg.BaseTemperature)
+			reheats++
+			stalledSteps = 0
+		}
+
+		// stop early if the best cost hasn't improved for long enough
+		if cfg.StallLimit > 0 && stalledSteps >= cfg.StallLimit {
+			break
+		}
+
+		if cfg.AdaptiveCooling && stepAccepted+stepRejected > 0 {
+			acceptance := float64(stepAccepted) / float64(stepAccepted+stepRejected)
+			coolingCfg.CoolingRate = adaptCoolingRate(coolingCfg.CoolingRate, cfg.CoolingRate, lastAcceptance, acceptance)
+			lastAcceptance = acceptance
+		}
+
+		// Cool all of the goroutines, starting again from the base temperature if there's time left once they've cooled
+		baseTemperature = cool(baseTemperature, coolingCfg)
+		if cfg.MaxTime > 0 && baseTemperature <= cfg.FinalTemperature {
+			baseTemperature = cfg.BaseTemperature
+		}
+
+		if cfg.Checkpoint != nil && runStats.Steps%checkpointSteps == 0 {
+			cfg.Checkpoint(Checkpoint{Seed: seed, Step: runStats.Steps, Temperature: baseTemperature, Cost: bestCost, Assignment: Solution{Assignment: bestSolution}.Tables()})
+		}
+	}
+
+	// clean up any improving swaps the random moves missed, which -maxtime's time limit (which has passed by now) doesn't
+	// stop - only the caller cancelling does
+	if cfg.Polish {
+		var evaluations int
+		bestCost, evaluations = polish(ctx, bestSolution, movable, s, costFunction, bestCost)
+		runStats.Evaluations += evaluations
+		pool.offer(bestSolution, bestCost)
+	}
+
+	// the last checkpoint is where the run stopped, so that an interrupted run can be resumed from it
+	if cfg.Checkpoint != nil {
+		cfg.Checkpoint(Checkpoint{Seed: seed, Step: runStats.Steps, Temperature: baseTemperature, Cost: bestCost, Assignment: Solution{Assignment: bestSolution}.Tables()})
+	}
+
+	runStats.FinalCost = bestCost
+	runStats.Reheats = reheats
+	if proposed := accepted + rejected; proposed > 0 {
+		runStats.AcceptanceRatio = float64(accepted) / float64(proposed)
+	}
+	runStats.ElapsedSeconds = time.Since(start).Seconds()
+	return bestSolution, runStats, ctx.Err()
+}
+
+// greedy seats everyone as the greedy initialisation would, without annealing, so that annealing can be compared
+// against it. It takes the same arguments as anneal so that either can be used to solve
+func greedy(ctx context.Context, seed int64, people []Person, tables []table, warmStart []table, s scoring, o objective, cfg AnnealConfig, pool *solutionPool) (result []table, runStats Stats, err error) {
+	start := time.Now()
+	result = greedyInitialisation(rand.New(rand.NewSource(seed)), people, tables, s, o.cost)
+	runStats.InitialCost = o.cost(result, s)
+	runStats.FinalCost = runStats.InitialCost
+	pool.offer(result, runStats.FinalCost)
+	runStats.ElapsedSeconds = time.Since(start).Seconds()
+	return result, runStats, ctx.Err()
+}
+
+// the most people (besides anyone pinned) the optimal solver will seat, as it tries every way of seating them
+const optimalMaxPeople = 12
+
+// optimal tries every way of seating the people in the seats after any pinned people (keeping every table at its
+// minimum), and returns the best, so that annealing can be checked against it. It takes the same arguments as anneal
+// so that either can be used to solve, but is only practical for a handful of people
+func optimal(ctx context.Context, seed int64, people []Person, tables []table, warmStart []table, s scoring, o objective, cfg AnnealConfig, pool *solutionPool) (result []table, runStats Stats, err error) {
+	start := time.Now()
+	assignment := tables
+	shortfall := 0
+	for _, table := range assignment {
+		for seat := table.pinned; seat < len(table.people); seat++ {
+			table.people[seat] = Person{empty: true}
+		}
+		if occupied(table) < table.minimum {
+			shortfall += table.minimum - occupied(table)
+		}
+	}
+
+	// shortfall is how many more people the tables below their minimum need, which everyone left has to make up
+	var search func(next int, shortfall int)
+	search = func(next int, shortfall int) {
+		if result != nil && ctx.Err() != nil {
+			return
+		}
+		if next == len(people) {
+			cost := o.cost(assignment, s)
+			runStats.Evaluations++
+			pool.offer(assignment, cost)
+			if result == nil || cost > runStats.FinalCost {
+				result, runStats.FinalCost = copyAssignment(assignment), cost
+			}
+			return
+		}
+		if shortfall > len(people)-next {
+			return
+		}
+		person := people[next]
+		for _, table := range assignment {
+			seat := emptySeat(table)
+			if seat == -1 {
+				continue
+			}
+			short := 0
+			if occupied(table) < table.minimum {
+				short = 1
+			}
+			table.people[seat] = person
+			table.seat(person)
+			search(next+1, shortfall-short)
+			table.unseat(person)
+			table.people[seat] = Person{empty: true}
+		}
+	}
+	search(0, shortfall)
+	if result == nil {
+		return nil, runStats, fmt.Errorf("there's no way of seating everyone with every table at its minimum")
+	}
+	runStats.InitialCost = runStats.FinalCost
+	runStats.ElapsedSeconds = time.Since(start).Seconds()
+	return result, runStats, ctx.Err()
+}
+
+// the number of temperature steps between checkpoints
+const checkpointSteps = 10
+
+// the number of random moves made to estimate the base temperature
+const temperatureWalkSteps = 100
+
+// estimateTemperature takes a random walk from the assignment (without changing it), and returns the temperature at
+// which the average move to a worse solution would be accepted with the target probability - or 0 if no move made it
+// worse. It also returns the number of times the cost function was called
+func estimateTemperature(rng *rand.Rand, assignment []table, movable []int, s scoring, costFunction func([]table, scoring) float64, swapCount int, moveChance float64, target float64) (float64, int) {
+	walk := copyAssignment(assignment)
+	cost := costFunction(walk, s)
+	worsened, worse := 0.0, 0
+	var swaps []swap
+	for step := 0; step < temperatureWalkSteps; step++ {
+		swaps = getNeighbour(rng, walk, movable, swapCount, moveChance, swaps[:0])
+		newCost := costFunction(walk, s)
+		if newCost < cost {
+			worsened += cost - newCost
+			worse++
+		}
+		cost = newCost
+	}
+	if worse == 0 {
+		return 0, temperatureWalkSteps + 1
+	}
+	return -(worsened / float64(worse)) / math.Log(target), temperatureWalkSteps + 1
+}
+
+// shouldExchange decides whether a hotter annealer's solution should be swapped with the next coldest one's
+func shouldExchange(rng *rand.Rand, exchange ReplicaExchange, hotCost float64, coldCost float64, hotTemperature float64, coldTemperature float64) bool {
+	if hotCost > coldCost {
+		return true
+	}
+	if exchange != Metropolis {
+		return false
+	}
+	// the chance of a worse solution being passed down falls with how much worse it is and how far apart the
+	// temperatures are
+	return math.Exp((hotCost-coldCost)*(1/coldTemperature-1/hotTemperature)) > rng.Float64()
+}
+
+// cool returns the temperature for the next step under the configured cooling schedule
+func cool(temperature float64, cfg AnnealConfig) float64 {
+	switch cfg.CoolingSchedule {
+	case Linear:
+		// take equal steps from the base to the final temperature, landing on it exactly rather than just above
+		step := (cfg.BaseTemperature - cfg.FinalTemperature) / float64(cfg.CoolingSteps)
+		if temperature-step < cfg.FinalTemperature+step/2 {
+			return cfg.FinalTemperature
+		}
+		return temperature - step
+	default:
+		return temperature * cfg.CoolingRate
+	}
+}
+
+// the target band for the fraction of moves accepted in a step with adaptive cooling, as a fraction of the last step's -
+// falling below it slows the cooling, and staying above it speeds it back up
+const (
+	adaptiveFastestDrop = 0.5
+	adaptiveSlowestDrop = 0.9
+)
+
+// the closest to 1 that adaptive cooling takes the cooling rate, so that annealing still finishes
+const adaptiveSlowestRate = 0.999
+
+// adaptCoolingRate returns the cooling rate for the next step, given the rate for the last one and the fraction of moves
+// accepted in the last two steps. If the fraction dropped by too much the cooling is slowed (halving how much the
+// temperature falls, on a log scale), and if it dropped by too little it is sped back up (to at most the given rate)
+func adaptCoolingRate(rate float64, configured float64, lastAcceptance float64, acceptance float64) float64 {
+	if lastAcceptance <= 0 {
+		return rate
+	}
+	switch drop := acceptance / lastAcceptance; {
+	case drop < adaptiveFastestDrop:
+		return math.Min(math.Sqrt(rate), adaptiveSlowestRate)
+	case drop > adaptiveSlowestDrop:
+		return math.Max(rate*rate, configured)
+	default:
+		return rate
+	}
+}
+
+// annealerCandidateIterator runs the annealing process as annealerInternalIterator does, but at each iteration the
+// candidate workers each make a neighbour of their own copy of the solution at once, and only the best of them is
+// considered for acceptance. The copies are kept in step by making each accepted swap on all of them
+func annealerCandidateIterator(ctx context.Context, rng *rand.Rand, candidateSolution []table, movable []int, s scoring, o objective, temperature float64, internalIterations int, swapCount int, moveChance float64, workers *candidateWorkers) (updatedSolution []table, updatedCost float64, accepted int, rejected int) {
+	updatedSolution = candidateSolution
+	updatedCost = o.cost(updatedSolution, s)
+	partsCost, fromParts := o.(partsObjective)
+	job := &candidateJob{movable: movable, s: s, o: o, swapCount: swapCount, moveChance: moveChance}
+
+	// as with the serial iterator, keep the parts of the cost from each table when the cost can be given from them, so
+	// that each worker only looks at the tables its neighbour changes (the parts are only changed between iterations)
+	if fromParts {
+		job.parts = make([]costParts, len(updatedSolution))
+		for tableNo := range updatedSolution {
+			job.parts[tableNo] = partsCost.tableParts(updatedSolution, tableNo, s)
+			job.totalParts = job.totalParts.add(job.parts[tableNo])
+		}
+		job.highest = getHighestCost(updatedSolution, s)
+	}
+	workers.reset(rng, updatedSolution)
+
+	for i := 0; i < internalIterations; i++ {
+		select {
+		case <-ctx.Done():
+			return updatedSolution, updatedCost, accepted, rejected
+		default:
+		}
+
+		candidates := workers.run(job)
+		best := 0
+		for w := 1; w < len(candidates); w++ {
+			if candidates[w].cost > candidates[best].cost {
+				best = w
+			}
+		}
+		if candidates[best].cost <= updatedCost && acceptanceProbability(updatedCost, candidates[best].cost, temperature) <= rng.Float64() {
+			rejected++
+			continue
+		}
+		accepted++
+		updatedCost = candidates[best].cost
+		for _, move := range candidates[best].swaps {
+			applySwap(updatedSolution, move)
+			workers.apply(move)
+		}
+		if fromParts {
+			for _, tableNo := range candidates[best].affected {
+				tablePartsNow := partsCost.tableParts(updatedSolution, tableNo, s)
+				job.totalParts = job.totalParts.sub(job.parts[tableNo]).add(tablePartsNow)
+				job.parts[tableNo] = tablePartsNow
+			}
+		}
+	}
+
+	// adding up the parts can drift from the true cost over many neighbours, so finish with the exact cost
+	if fromParts {
+		updatedCost = o.cost(updatedSolution, s)
+	}
+	return updatedSolution, updatedCost, accepted, rejected
+}
+
+// swapsAt returns the number of swaps made to get a neighbouring solution at the given temperature. When decreasing,
+// this falls from the swap count at the base temperature (or hotter) to 1 at the final temperature, in proportion to
+// how far through the cooling schedule the temperature is
+func swapsAt(temperature float64, cfg AnnealConfig) int {
+	if cfg.SwapSchedule != DecreasingSwaps {
+		return cfg.SwapCount
+	}
+	var left float64
+	switch cfg.CoolingSchedule {
+	case Linear:
+		left = (temperature - cfg.FinalTemperature) / (cfg.BaseTemperature - cfg.FinalTemperature)
+	default:
+		left = math.Log(temperature/cfg.FinalTemperature) / math.Log(cfg.BaseTemperature/cfg.FinalTemperature)
+	}
+	left = math.Max(0, math.Min(1, left))
+	return 1 + int(math.Round(left*float64(cfg.SwapCount-1)))
+}
+
+// Gets a neighbouring candidate solution and runs the probibalistic steps of the annealing process as many times as
+// specified by the internalIterations count (or until the context is cancelled), returning the resulting solution, its
+// cost and how many neighbours were accepted and rejected.
+func annealerInternalIterator(ctx context.Context, rng *rand.Rand, candidateSolution []table, movable []int, s scoring, o objective, temperature float64, internalIterations int, swapCount int, moveChance float64) (updatedSolution []table, updatedCost float64, accepted int, rejected int) {
+
+	// each annealer's solution is its own, so it is changed in place rather than copied
+	updatedSolution = candidateSolution
+	updatedCost = o.cost(updatedSolution, s)
+	partsCost, fromParts := o.(partsObjective)
+
+	// when the cost can be given from its parts, keep the parts from each table so that only the tables a neighbour
+	// changes need to be looked at again
+	var parts, candidateParts []costParts
+	var totalParts costParts
+	var affected []int
+	swaps := make([]swap, 0, swapCount)
+	highest := 0.0
+	if fromParts {
+		parts = make([]costParts, len(updatedSolution))
+		for tableNo := range updatedSolution {
+			parts[tableNo] = partsCost.tableParts(updatedSolution, tableNo, s)
+			totalParts = totalParts.add(parts[tableNo])
+		}
+		highest = getHighestCost(updatedSolution, s)
+	}
+
+	for i := 0; i < internalIterations; i++ {
+		select {
+		case <-ctx.Done():
+			return updatedSolution, updatedCost, accepted, rejected
+		default:
+		}
+
+		// the neighbour is made in place, so we keep hold of the swaps in case we need to undo them
+		swaps = getNeighbour(rng, updatedSolution, movable, swapCount, moveChance, swaps[:0])
+		var newCandidateCost float64
+		candidateTotal := totalParts
+		if fromParts {
+			affected = affectedTables(updatedSolution, swaps, affected[:0])
+			candidateParts = candidateParts[:0]
+			for _, tableNo := range affected {
+				tablePartsNow := partsCost.tableParts(updatedSolution, tableNo, s)
+				candidateParts = append(candidateParts, tablePartsNow)
+				candidateTotal = candidateTotal.sub(parts[tableNo]).add(tablePartsNow)
+			}
+			newCandidateCost = partsCost.fromParts(candidateTotal, highest)
+		} else {
+			newCandidateCost = o.cost(updatedSolution, s)
+		}
+
+		// if the cost is more then switch to that solution
+		if newCandidateCost > updatedCost {
+			updatedCost = newCandidateCost
+			accepted++
+			keepParts(parts, affected, candidateParts)
+			totalParts = candidateTotal
+
+			// And finally switch to a more costly solution randomly based on the acceptance probablity
+		} else {
+			ap := acceptanceProbability(updatedCost, newCandidateCost, temperature)
+
+			if ap > rng.Float64() {
+				updatedCost = newCandidateCost
+				accepted++
+				keepParts(parts, affected, candidateParts)
+				totalParts = candidateTotal
+			} else {
+				rejected++
+				// the neighbour was rejected, so undo its swaps in reverse order
+				for j := len(swaps) - 1; j >= 0; j-- {
+					undoSwap(updatedSolution, swaps[j])
+				}
+			}
+		}
+	}
+
+	// adding up the parts can drift from the true cost over many neighbours, so finish with the exact cost
+	if fromParts {
+		updatedCost = o.cost(updatedSolution, s)
+	}
+	return updatedSolution, updatedCost, accepted, rejected
+}
+
+// affectedTables appends the tables whose people's parts of the cost can change when the given swaps are made to
+// affected, without repeats - the tables swapped between, along with those next to them
+func affectedTables(assignment []table, swaps []swap, affected []int) []int {
+	for _, s := range swaps {
+		for _, tableNo := range [2]int{s.tableOne, s.tableTwo} {
+			affected = appendTable(affected, tableNo)
+			for _, adjacent := range assignment[tableNo].adjacent {
+				affected = appendTable(affected, adjacent)
+			}
+		}
+	}
+	return affected
+}
+
+// appendTable appends the table to the list if it isn't already there
+func appendTable(tables []int, tableNo int) []int {
+	for _, existing := range tables {
+		if existing == tableNo {
+			return tables
+		}
+	}
+	return append(tables, tableNo)
+}
+
+// keepParts records the parts of an accepted neighbour's affected tables
+func keepParts(parts []costParts, affected []int, candidateParts []costParts) {
+	for i, tableNo := range affected {
+		parts[tableNo] = candidateParts[i]
+	}
+}
+
+// a swap of the people sat in two seats at different tables
+type swap struct {
+	tableOne, seatOne int
+	tableTwo, seatTwo int
+}
+
+// the most times getNeighbour draws seats for a swap before deciding there's no neighbour to be had
+const maxNeighbourDraws = 10000
+
+// Turns the assignment into a neighbouring candidate solution in place using the given rng, appending the swaps that
+// were made to swaps (so that its space can be reused). Only the given movable tables are swapped between, and never
+// the seats of pinned people. Each swap is, with the given chance, a move of someone into an empty seat.
+func getNeighbour(rng *rand.Rand, assignment []table, movable []int, swapCount int, moveChance float64, swaps []swap) []swap {
+
+	cal := len(movable)
+
+	for i := 0; i < swapCount; i++ {
+		move := moveChance > 0 && rng.Float64() < moveChance
+
+		var randOne, randTwo, randThree, randFour int
+		for draws := 0; ; draws++ {
+			// with tables at their minimums there may be no neighbour at all, so give up rather than loop forever
+			if draws == maxNeighbourDraws {
+				return swaps
+			}
+
+			// generate two distinct random numbers so we know we are shuffling people in different tables
+			randOne = rng.Intn(cal)
+			randTwo = rng.Intn(cal - 1)
+
+			if randTwo >= randOne {
+				randTwo++
+			}
+			randOne, randTwo = movable[randOne], movable[randTwo]
+
+			// generate two further indexes for the seats actually there, after those taken by pinned people (empty
+			// seats hold an empty placeholder rather than a zero-value person, so are never marked as seated)
+			randThree = assignment[randOne].pinned + rng.Intn(len(assignment[randOne].people)-assignment[randOne].pinned)
+			randFour = assignment[randTwo].pinned + rng.Intn(len(assignment[randTwo].people)-assignment[randTwo].pinned)
+
+			// swapping two empty seats changes nothing, so try again rather than waste the iteration - and a move
+			// needs exactly one of the seats to be empty
+			emptyOne, emptyTwo := assignment[randOne].people[randThree].empty, assignment[randTwo].people[randFour].empty
+			if (emptyOne && emptyTwo) || (move && !emptyOne && !emptyTwo) {
+				continue
+			}
+
+			// moving someone into an empty seat can't leave their table with fewer than its minimum, so swap
+			// instead (nobody may be able to move, so a move isn't insisted on)
+			if emptyOne != emptyTwo && belowMinimumAfterMove(assignment[randOne], assignment[randTwo], emptyTwo) {
+				move = false
+				continue
+			}
+			break
+		}
+
+		swaps = append(swaps, swap{tableOne: randOne, seatOne: randThree, tableTwo: randTwo, seatTwo: randFour})
+		applySwap(assignment, swaps[len(swaps)-1])
+	}
+
+	return swaps
+}
+
+// belowMinimumAfterMove reports whether moving someone between the two tables would leave the table they leave with
+// fewer than its minimum - they leave the first table if the seat at the second is empty, and the second otherwise
+func belowMinimumAfterMove(tableOne table, tableTwo table, emptyTwo bool) bool {
+	leaving := tableTwo
+	if emptyTwo {
+		leaving = tableOne
+	}
+	return leaving.minimum > 0 && occupied(leaving)-1 < leaving.minimum
+}
+
+// polish hill-climbs from the assignment in place, repeatedly trying every swap between seats at different tables and
+// keeping any that strictly improve the cost, until none do (or the context is cancelled). It returns the new cost and
+// the number of times the cost function was called
+func polish(ctx context.Context, assignment []table, movable []int, s scoring, costFunction func([]table, scoring) float64, cost float64) (float64, int) {
+	evaluations := 0
+	for improved := true; improved && ctx.Err() == nil; {
+		improved = false
+		for i, tableOne := range movable {
+			for _, tableTwo := range movable[i+1:] {
+				for seatOne := assignment[tableOne].pinned; seatOne < len(assignment[tableOne].people); seatOne++ {
+					for seatTwo := assignment[tableTwo].pinned; seatTwo < len(assignment[tableTwo].people); seatTwo++ {
+						emptyOne, emptyTwo := assignment[tableOne].people[seatOne].empty, assignment[tableTwo].people[seatTwo].empty
+						if emptyOne && emptyTwo {
+							continue
+						}
+						if (emptyOne || emptyTwo) && belowMinimumAfterMove(assignment[tableOne], assignment[tableTwo], emptyTwo) {
+							continue
+						}
+						move := swap{tableOne: tableOne, seatOne: seatOne, tableTwo: tableTwo, seatTwo: seatTwo}
+						applySwap(assignment, move)
+						evaluations++
+						if newCost := costFunction(assignment, s); newCost > cost {
+							cost = newCost
+							improved = true
+						} else {
+							undoSwap(assignment, move)
+						}
+					}
+				}
+			}
+		}
+	}
+	return cost, evaluations
+}
+
+// hasEmptySeat reports whether any seat in the assignment is empty
+func hasEmptySeat(assignment []table) bool {
+	for _, table := range assignment {
+		for _, person := range table.people {
+			if person.empty {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// movableTables returns the indexes of the tables with seats that aren't taken by pinned people
+func movableTables(tables []table) (movable []int) {
+	for i, table := range tables {
+		if table.pinned < len(table.people) {
+			movable = append(movable, i)
+		}
+	}
+	return movable
+}
+
+// applySwap swaps the two people in the given seats, keeping track of who is sat at both tables
+func applySwap(assignment []table, s swap) {
+	tableOne := assignment[s.tableOne]
+	tableTwo := assignment[s.tableTwo]
+
+	personOne := tableOne.people[s.seatOne]
+	personTwo := tableTwo.people[s.seatTwo]
+
+	tableOne.people[s.seatOne], tableTwo.people[s.seatTwo] = personTwo, personOne
+
+	// empty seats are never marked as seated
+	if !personOne.empty {
+		tableOne.unseat(personOne)
+		tableTwo.seat(personOne)
+	}
+	if !personTwo.empty {
+		tableTwo.unseat(personTwo)
+		tableOne.seat(personTwo)
+	}
+}
+
+// undoSwap exactly reverses applySwap - swapping the same two seats again puts both people back
+func undoSwap(assignment []table, s swap) {
+	applySwap(assignment, s)
+}
+
+// costParts is what the cost functions are made up of, which can be added up person by person - so when people are
+// swapped, only the tables whose people's parts could change need to be looked at again
+type costParts struct {
+	sum     float64 // the sum function's cost, before any penalties
+	count   float64 // the count function's cost, before any penalties
+	penalty float64 // the penalty for each hard constraint broken
+}
+
+// add returns the two sets of parts added together
+func (c costParts) add(other costParts) costParts {
+	return costParts{sum: c.sum + other.sum, count: c.count + other.count, penalty: c.penalty + other.penalty}
+}
+
+// sub returns the other set of parts taken away from these
+func (c costParts) sub(other costParts) costParts {
+	return costParts{sum: c.sum - other.sum, count: c.count - other.count, penalty: c.penalty - other.penalty}
+}
+
+// preferenceWeight returns what each satisfied preference's weight is multiplied by
+func (s scoring) preferenceWeight() float64 {
+	if s.preferences == 0 {
+		return 1
+	}
+	return s.preferences
+}
+
+// personParts returns the parts of the cost that come from the person sat at the given table - the hard constraints
+// are their plus-one being sat with them, having at least the minimum satisfied, being sat with their group but not
+// with anyone they must be kept apart from (where pairs are only penalised once, from the first of the two) and not
+// being sat at a table they're forbidden from
+func personParts(assignment []table, tableNo int, person Person, s scoring) (c costParts) {
+	table := assignment[tableNo]
+	plusOne, exists := s.plusOnes[person.id]
+	if exists && !table.has(plusOne) {
+		c.penalty += s.penalty
+	}
+	if satisfiedAtTable(table, person) < s.minSatisfied {
+		c.penalty += s.penalty
+	}
+	for _, id := range s.apart[person.id] {
+		if person.id < id && table.has(id) {
+			c.penalty += s.penalty
+		}
+	}
+	for _, id := range s.together[person.id] {
+		if person.id < id && !table.has(id) {
+			c.penalty += s.penalty
+		}
+	}
+	if isForbidden(s.forbidden, person.id, tableNo) {
+		c.penalty += s.penalty
+	}
+
+	// a preference with a negative weight (e.g. from a matrix) only lowers the sum, and is never counted as satisfied
+	weight := s.preferenceWeight()
+	for _, preference := range person.Preferences {
+		if matches := table.matches(preference, person); matches > 0 {
+			c.sum += weight * preference.Weight * float64(matches)
+			if preference.Weight > 0 {
+				c.count = weight
+			}
+		} else if matches := adjacentMatches(assignment, tableNo, preference, person); matches > 0 {
+			c.sum += s.adjacentCredit * weight * preference.Weight * float64(matches)
+			if preference.Weight > 0 && c.count < weight {
+				c.count = s.adjacentCredit * weight
+			}
+		}
+	}
+	avoidPenalty := s.avoidPenalty * float64(avoidedAtTable(table, person))
+	c.sum -= avoidPenalty
+	c.count -= avoidPenalty
+	if s.lonelyPenalty != 0 && lonelyAtTable(table, person) {
+		c.sum -= s.lonelyPenalty
+		c.count -= s.lonelyPenalty
+	}
+	if s.desirability != 0 {
+		desirability := s.desirability * person.VIP * table.desirability
+		c.sum += desirability
+		c.count += desirability
+	}
+	if previous, ok := s.previous[person.id]; ok && previous != tableNo {
+		c.sum -= s.stability
+		c.count -= s.stability
+	}
+
+	// each pair sat together again is only penalised once, from the first of the two
+	if s.met != nil {
+		for _, other := range table.people {
+			if !other.empty && person.id < other.id && s.met[person.id][other.id] > 0 {
+				metPenalty := s.metPenalty * float64(s.met[person.id][other.id])
+				c.sum -= metPenalty
+				c.count -= metPenalty
+			}
+		}
+	}
+
+	// each mutual pair is only given the bonus once, from the first of the two
+	for _, id := range s.mutual[person.id] {
+		if person.id < id && table.has(id) {
+			c.sum += s.mutualBonus
+		}
+	}
+	return c
+}
+
+// tableParts returns the parts of the cost that come from everyone sat at the given table, along with how unbalanced
+// the table is
+func tableParts(assignment []table, tableNo int, s scoring) (c costParts) {
+	for _, person := range assignment[tableNo].people {
+		if !person.empty {
+			c = c.add(personParts(assignment, tableNo, person, s))
+		}
+	}
+	if s.balance != 0 {
+		imbalance := s.balance * tableImbalance(assignment[tableNo], s)
+		c.sum -= imbalance
+		c.count -= imbalance
+	}
+	return c
+}
+
+// tableImbalance returns how far the mix of the balanced attribute at the table is from everyone's: the number of
+// people with each value, less how many there would be if the table had the same mix as everyone, added up (ignoring
+// the sign) over the values
+func tableImbalance(t table, s scoring) (imbalance float64) {
+	counts := make([]float64, len(s.shares))
+	total := 0.0
+	for _, person := range t.people {
+		if !person.empty && s.balanced[person.id] >= 0 {
+			counts[s.balanced[person.id]]++
+			total++
+		}
+	}
+	for value, share := range s.shares {
+		imbalance += math.Abs(counts[value] - total*share)
+	}
+	return imbalance
+}
+
+// getImbalance returns how far the mix of the balanced attribute at each table is from everyone's, added up over the
+// tables (see tableImbalance)
+func getImbalance(assignment []table, s scoring) (imbalance float64) {
+	if s.shares == nil {
+		return 0
+	}
+	for _, table := range assignment {
+		imbalance += tableImbalance(table, s)
+	}
+	return imbalance
+}
+
+// getCostParts returns the parts of the cost that come from everyone in the assignment
+func getCostParts(assignment []table, s scoring) (c costParts) {
+	for tableNo := range assignment {
+		c = c.add(tableParts(assignment, tableNo, s))
+	}
+	return c
+}
+
+// the cost function is the sum of the weights of satisfied preferences, with partial credit for preferences sat at
+// adjacent tables and a bonus for mutual preferences, less a penalty for everyone sat with someone they want to avoid
+func sumFunction(assignment []table, s scoring) (cost float64) {
+	return sumOfParts(getCostParts(assignment, s), 0)
+}
+
+// the cost function is the count of people with >= 1 preferences, with partial credit for people whose closest
+// preference is at an adjacent table, less a penalty for everyone sat with someone they want to avoid
+func countFunction(assignment []table, s scoring) (cost float64) {
+	return countOfParts(getCostParts(assignment, s), 0)
+}
+
+// this cost function presents a hybrid - prioritising everyone having >= 1 preference whilst keeping as many preferences
+func hybridFunction(assignment []table, s scoring) (cost float64) {
+	return hybridOfParts(getCostParts(assignment, s), getHighestCost(assignment, s))
+}
+
+// sumOfParts gives the sum function's cost from its parts, less the penalty for any hard constraints broken
+func sumOfParts(c costParts, highest float64) float64 {
+	return c.sum - c.penalty
+}
+
+// countOfParts gives the count function's cost from its parts, less the penalty for any hard constraints broken
+func countOfParts(c costParts, highest float64) float64 {
+	return c.count - c.penalty
+}
+
+// hybridOfParts gives the hybrid function's cost from its parts, given the highest of the number of people and the
+// highest possible sum
+func hybridOfParts(c costParts, highest float64) float64 {
+	return countOfParts(c, highest)*highest + sumOfParts(c, highest)
+}
+
+// getHighestCost returns the highest of the number of people and the highest possible sum, which the hybrid function
+// weights the count by
+func getHighestCost(assignment []table, s scoring) float64 {
+	return math.Max(float64(getNoOfPeople(assignment)), getHighestSum(assignment, s))
+}
+
+// satisfiedAtTable counts how many of the person's preferences (with a positive weight) are sat at the given table
+func satisfiedAtTable(t table, p Person) (satisfied int) {
+	for _, preference := range p.Preferences {
+		if preference.Weight > 0 && t.matches(preference, p) > 0 {
+			satisfied++
+		}
+	}
+	return satisfied
+}
+
+// wanted counts the person's preferences with a positive weight, which are the ones that can be satisfied - a
+// preference with a negative weight is someone they'd rather not be sat with
+func wanted(p Person) (preferences int) {
+	for _, preference := range p.Preferences {
+		if preference.Weight > 0 {
+			preferences++
+		}
+	}
+	return preferences
+}
+
+// avoidedAtTable counts how many of the people the person wants to avoid are sat at the given table
+func avoidedAtTable(t table, p Person) (avoided int) {
+	for _, id := range p.avoidIDs {
+		if t.has(id) {
+			avoided++
+		}
+	}
+	return avoided
+}
+
+// getAvoided returns the number of times someone is sat with a person they want to avoid
+func getAvoided(assignment []table) int {
+	current := 0
+	for _, table := range assignment {
+		for _, person := range table.people {
+			current += avoidedAtTable(table, person)
+		}
+	}
+	return current
+}
+
+// lonelyAtTable reports whether the person prefers anyone (with a positive weight) but none of them are sat at the
+// given table
+func lonelyAtTable(t table, p Person) bool {
+	lonely := false
+	for _, preference := range p.Preferences {
+		if preference.Weight <= 0 {
+			continue
+		}
+		if t.matches(preference, p) > 0 {
+			return false
+		}
+		lonely = true
+	}
+	return lonely
+}
+
+// getLonely returns the number of people sat with none of their preferences, out of those who have any
+func getLonely(assignment []table) int {
+	current := 0
+	for _, table := range assignment {
+		for _, person := range table.people {
+			if !person.empty && lonelyAtTable(table, person) {
+				current++
+			}
+		}
+	}
+	return current
+}
+
+// getBelowMinimum returns the number of people sat with fewer than minSatisfied of their preferences
+func getBelowMinimum(assignment []table, minSatisfied int) int {
+	current := 0
+	for _, table := range assignment {
+		for _, person := range table.people {
+			if !person.empty && satisfiedAtTable(table, person) < minSatisfied {
+				current++
+			}
+		}
+	}
+	return current
+}
+
+// checkMinSatisfied returns an error if minSatisfied preferences can't possibly be met for everyone, either because
+// someone hasn't asked for enough people or because no table is big enough
+func checkMinSatisfied(people []Person, tables []table, minSatisfied int) error {
+	if minSatisfied <= 0 {
+		return nil
+	}
+	largestTable := 0
+	for _, table := range tables {
+		if table.capacity > largestTable {
+			largestTable = table.capacity
+		}
+	}
+	if largestTable-1 < minSatisfied {
+		return fmt.Errorf("cannot satisfy %d preferences for everyone when the largest table seats %d", minSatisfied, largestTable)
+	}
+	for _, person := range people {
+		distinct := make(map[string]bool)
+		for _, preference := range person.Preferences {
+			if preference.Name != person.Name && preference.Weight > 0 {
+				distinct[preference.Name] = true
+			}
+		}
+		if len(distinct) < minSatisfied {
+			return fmt.Errorf("cannot satisfy %d preferences for %s, who only has %d", minSatisfied, person.Name, len(distinct))
+		}
+	}
+	return nil
+}
+
+// adjacentMatches returns how many people the preference is for are sat at the tables next to the given one
+func adjacentMatches(assignment []table, tableNo int, preference Preference, self Person) (matches int) {
+	for _, adjacent := range assignment[tableNo].adjacent {
+		matches += assignment[adjacent].matches(preference, self)
+	}
+	return matches
+}
+
+// getTotalPrefs returns the total number of preferences across the assignment
+func getTotalPrefs(assignment []table) int {
+	current := 0
+	for _, table := range assignment {
+		for _, person := range table.people {
+			current += wanted(person)
+		}
+	}
+	return current
+}
+
+// getHighestSum returns the sum function's cost if every preference were satisfied, including the mutual bonuses
+func getHighestSum(assignment []table, s scoring) float64 {
+	mutualPairs := 0
+	for _, ids := range s.mutual {
+		mutualPairs += len(ids)
+	}
+	return s.preferenceWeight()*getTotalWeight(assignment) + s.mutualBonus*float64(mutualPairs/2)
+}
+
+// getDesirability returns the sum of each person's VIP score times the desirability of the table they're sat at
+func getDesirability(assignment []table) (desirability float64) {
+	for _, table := range assignment {
+		for _, person := range table.people {
+			if !person.empty {
+				desirability += person.VIP * table.desirability
+			}
+		}
+	}
+	return desirability
+}
+
+// getMoved returns the number of people sat at a different table from the one they were sat at before
+func getMoved(assignment []table, s scoring) (moved int) {
+	for tableNo, table := range assignment {
+		for _, person := range table.people {
+			if previous, ok := s.previous[person.id]; !person.empty && ok && previous != tableNo {
+				moved++
+			}
+		}
+	}
+	return moved
+}
+
+// getMutualTogether returns the number of mutual pairs sat at the same table
+func getMutualTogether(assignment []table, s scoring) int {
+	current := 0
+	for _, table := range assignment {
+		for _, person := range table.people {
+			if person.empty {
+				continue
+			}
+			for _, other := range s.mutual[person.id] {
+				if person.id < other && table.has(other) {
+					current++
+				}
+			}
+		}
+	}
+	return current
+}
+
+// getMutual finds, for each person, the people whose preference for them is reciprocated, by index
+func getMutual(people []Person) map[int][]int {
+	prefers := make([]map[int]bool, len(people))
+	for _, person := range people {
+		prefers[person.id] = make(map[int]bool)
+		for _, preference := range person.Preferences {
+			if preference.id >= 0 && !preference.isTag && preference.Weight > 0 {
+				prefers[person.id][preference.id] = true
+			}
+		}
+	}
+
+	mutual := make(map[int][]int)
+	for id, preferred := range prefers {
+		for other := range preferred {
+			if other != id && prefers[other][id] {
+				mutual[id] = append(mutual[id], other)
+			}
+		}
+	}
+	return mutual
+}
+
+// getSatisfaction returns the weight of the preferences sat at the same table as a percentage of the total weight of
+// preferences (where a preference for a tag is satisfied by everyone with the tag). As in the total, only preferences
+// with a positive weight count, so people sat with someone they'd rather avoid don't take it below 0
+func getSatisfaction(assignment []table) float64 {
+	totalWeight := getTotalWeight(assignment)
+	if totalWeight == 0 {
+		return 100
+	}
+	satisfied := 0.0
+	for _, table := range assignment {
+		for _, person := range table.people {
+			for _, preference := range person.Preferences {
+				if preference.Weight > 0 {
+					satisfied += preference.Weight * float64(table.matches(preference, person))
+				}
+			}
+		}
+	}
+	return satisfied / totalWeight * 100
+}
+
+// getTotalWeight returns the total weight of the preferences across the assignment
+func getTotalWeight(assignment []table) float64 {
+	// a preference for a tag is worth its weight for everyone else with the tag
+	var tagged []int
+	for _, table := range assignment {
+		if tagged == nil {
+			tagged = make([]int, len(table.tagged))
+		}
+		for tag, count := range table.tagged {
+			tagged[tag] += count
+		}
+	}
+
+	current := 0.0
+	for _, table := range assignment {
+		for _, person := range table.people {
+			for _, preference := range person.Preferences {
+				if preference.Weight <= 0 {
+					continue
+				}
+				if !preference.isTag {
+					current += preference.Weight
+				} else if preference.id >= 0 {
+					others := tagged[preference.id]
+					for _, tag := range person.tagIDs {
+						if tag == preference.id {
+							others--
+						}
+					}
+					current += preference.Weight * float64(others)
+				}
+			}
+		}
+	}
+	return current
+}
+
+// getNoOfPeople returns the number of people in the assignment, not counting empty seats
+func getNoOfPeople(assignment []table) int {
+	current := 0
+	for _, table := range assignment {
+		current += occupied(table)
+	}
+	return current
+}
+
+// acceptanceProbability gives the chance of moving from a solution with the old cost to one with the new cost, which
+// is always between 0 and 1 - a temperature of 0 (or one that has underflowed to it) never accepts
+func acceptanceProbability(oldCost float64, newCost float64, temperature float64) (probability float64) {
+	if temperature <= 0 {
+		return 0
+	}
+	exponent := (newCost - oldCost) / temperature
+	if math.IsNaN(exponent) {
+		return 0
+	}
+	if exponent >= 0 {
+		return 1
+	}
+	return math.Exp(exponent)
+}
+
+// randomly assigns people to the seats after any pinned people, shuffling with the given rng - any seats left over
+// are filled with empty placeholders, which are shuffled in with everyone else. The people are shuffled in a copy, so
+// the caller's order is kept (the tables, however, are filled in place)
+func randomInitialisation(rng *rand.Rand, people []Person, tables []table, forbidden map[int][]int) (assignment []table) {
+	assignment = tables
+
+	seats := 0
+	for _, table := range assignment {
+		seats += table.capacity - table.pinned
+	}
+	people = append([]Person(nil), people...)
+	for len(people) < seats {
+		people = append(people, Person{empty: true})
+	}
+
+	for i := range people {
+		j := rng.Intn(i + 1)
+		people[i], people[j] = people[j], people[i]
+	}
+
+	// now just fill forwards
+	pos := 0
+	for i, table := range assignment {
+		copy(assignment[i].people[table.pinned:], people[pos:pos+table.capacity-table.pinned])
+		for _, person := range assignment[i].people[table.pinned:] {
+			if !person.empty {
+				table.seat(person)
+			}
+		}
+		pos += table.capacity - table.pinned
+	}
+
+	fillMinimums(rng, assignment)
+	moveForbidden(rng, assignment, forbidden)
+	return assignment
+}
+
+// moveForbidden swaps anyone sat at a table they're forbidden from with someone (or an empty seat, if that leaves their
+// table with at least its minimum) at a table they aren't forbidden from, where that person isn't forbidden from theirs
+// - chosen with the given rng. Anyone who can't be moved is left to the annealer
+func moveForbidden(rng *rand.Rand, assignment []table, forbidden map[int][]int) {
+	for i := range assignment {
+		for seat := assignment[i].pinned; seat < len(assignment[i].people); seat++ {
+			person := assignment[i].people[seat]
+			if person.empty || !isForbidden(forbidden, person.id, i) {
+				continue
+			}
+			var moves []swap
+			for j := range assignment {
+				if j == i || isForbidden(forbidden, person.id, j) {
+					continue
+				}
+				for other := assignment[j].pinned; other < len(assignment[j].people); other++ {
+					occupant := assignment[j].people[other]
+					if occupant.empty && belowMinimumAfterMove(assignment[i], assignment[j], true) {
+						continue
+					}
+					if !occupant.empty && isForbidden(forbidden, occupant.id, i) {
+						continue
+					}
+					moves = append(moves, swap{tableOne: i, seatOne: seat, tableTwo: j, seatTwo: other})
+				}
+			}
+			if len(moves) > 0 {
+				applySwap(assignment, moves[rng.Intn(len(moves))])
+			}
+		}
+	}
+}
+
+// isForbidden returns whether the person with the given index is forbidden from the table with the given index
+func isForbidden(forbidden map[int][]int, id int, tableNo int) bool {
+	for _, forbiddenNo := range forbidden[id] {
+		if forbiddenNo == tableNo {
+			return true
+		}
+	}
+	return false
+}
+
+// greedily assigns people to the seats after any pinned people, from those with the most preferences to the fewest
+// (ties broken with the given rng), sitting each at the table that most improves the cost so far - any seats left
+// over are left empty
+func greedyInitialisation(rng *rand.Rand, people []Person, tables []table, s scoring, costFunction func([]table, scoring) float64) (assignment []table) {
+	assignment = tables
+	for _, table := range assignment {
+		for seat := table.pinned; seat < len(table.people); seat++ {
+			table.people[seat] = Person{empty: true}
+		}
+	}
+
+	people = append([]Person(nil), people...)
+	rng.Shuffle(len(people), func(i, j int) {
+		people[i], people[j] = people[j], people[i]
+	})
+	sort.SliceStable(people, func(i, j int) bool {
+		return len(people[i].Preferences) > len(people[j].Preferences)
+	})
+
+	for _, person := range people {
+		var best []int
+		bestCost := 0.0
+		for tableNo, table := range assignment {
+			seat := emptySeat(table)
+			if seat == -1 {
+				continue
+			}
+			table.people[seat] = person
+			table.seat(person)
+			cost := costFunction(assignment, s)
+			table.unseat(person)
+			table.people[seat] = Person{empty: true}
+
+			if len(best) == 0 || cost > bestCost {
+				best, bestCost = []int{tableNo}, cost
+			} else if cost == bestCost {
+				best = append(best, tableNo)
+			}
+		}
+		table := assignment[best[rng.Intn(len(best))]]
+		table.people[emptySeat(table)] = person
+		table.seat(person)
+	}
+
+	fillMinimums(rng, assignment)
+	return assignment
+}
+
+// clusteredInitialisation seats people in clusters of those who prefer each other, so that friends start out together
+// rather than scattered across the tables. Pairs are joined into clusters from the most strongly linked (by the
+// weights of their preferences for each other, with ties broken with the given rng) while the cluster still fits at the
+// roomiest table. Clusters are then sat from the largest down at the table with the most empty seats, which a cluster
+// too big for any table left is split across
+func clusteredInitialisation(rng *rand.Rand, people []Person, tables []table, forbidden map[int][]int) (assignment []table) {
+	assignment = tables
+	free := make([]int, len(assignment))
+	largest := 0
+	for tableNo, table := range assignment {
+		for seat := table.pinned; seat < len(table.people); seat++ {
+			table.people[seat] = Person{empty: true}
+		}
+		free[tableNo] = table.capacity - table.pinned
+		if free[tableNo] > largest {
+			largest = free[tableNo]
+		}
+	}
+
+	// pairs are linked by the total weight of their (positive) preferences for each other, in either direction
+	position := make(map[int]int)
+	for i, person := range people {
+		position[person.id] = i
+	}
+	type link struct {
+		one, two int
+		weight   float64
+	}
+	var links []link
+	linked := make(map[[2]int]int)
+	for i, person := range people {
+		for _, preference := range person.Preferences {
+			j, unpinned := position[preference.id]
+			if preference.isTag || !unpinned || i == j || preference.Weight <= 0 {
+				continue
+			}
+			pair := [2]int{i, j}
+			if j < i {
+				pair = [2]int{j, i}
+			}
+			if _, ok := linked[pair]; !ok {
+				linked[pair] = len(links)
+				links = append(links, link{one: pair[0], two: pair[1]})
+			}
+			links[linked[pair]].weight += preference.Weight
+		}
+	}
+	rng.Shuffle(len(links), func(i, j int) {
+		links[i], links[j] = links[j], links[i]
+	})
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].weight > links[j].weight
+	})
+
+	parent := make([]int, len(people))
+	size := make([]int, len(people))
+	for i := range parent {
+		parent[i], size[i] = i, 1
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, l := range links {
+		one, two := find(l.one), find(l.two)
+		if one != two && size[one]+size[two] <= largest {
+			parent[two] = one
+			size[one] += size[two]
+		}
+	}
+
+	byRoot := make(map[int][]Person)
+	var roots []int
+	for i, person := range people {
+		root := find(i)
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], person)
+	}
+	rng.Shuffle(len(roots), func(i, j int) {
+		roots[i], roots[j] = roots[j], roots[i]
+	})
+	sort.SliceStable(roots, func(i, j int) bool {
+		return size[roots[i]] > size[roots[j]]
+	})
+
+	roomiest := func() int {
+		best := 0
+		for tableNo := range free {
+			if free[tableNo] > free[best] {
+				best = tableNo
+			}
+		}
+		return best
+	}
+	for _, root := range roots {
+		tableNo := roomiest()
+		for _, person := range byRoot[root] {
+			if free[tableNo] == 0 {
+				tableNo = roomiest()
+			}
+			table := assignment[tableNo]
+			table.people[emptySeat(table)] = person
+			table.seat(person)
+			free[tableNo]--
+		}
+	}
+
+	fillMinimums(rng, assignment)
+	moveForbidden(rng, assignment, forbidden)
+	return assignment
+}
+
+// emptySeat returns the first empty seat at the table after any pinned people, or -1 if it's full
+func emptySeat(t table) int {
+	for seat := t.pinned; seat < len(t.people); seat++ {
+		if t.people[seat].empty {
+			return seat
+		}
+	}
+	return -1
+}
+
+// fillMinimums moves people into any table with fewer than its minimum, from tables that can spare them (chosen with
+// the given rng)
+func fillMinimums(rng *rand.Rand, assignment []table) {
+	for i := range assignment {
+		for occupied(assignment[i]) < assignment[i].minimum {
+			var spare []swap
+			for j, donor := range assignment {
+				if occupied(donor) <= donor.minimum {
+					continue
+				}
+				for seat := donor.pinned; seat < len(donor.people); seat++ {
+					if !donor.people[seat].empty {
+						spare = append(spare, swap{tableOne: j, seatOne: seat})
+					}
+				}
+			}
+			move := spare[rng.Intn(len(spare))]
+			move.tableTwo, move.seatTwo = i, emptySeat(assignment[i])
+			applySwap(assignment, move)
+		}
+	}
+}
+
+// occupied returns the number of people sat at the table, not counting empty seats
+func occupied(t table) int {
+	current := 0
+	for _, person := range t.people {
+		if !person.empty {
+			current++
+		}
+	}
+	return current
+}
+
+// copies the assignment
+func copyAssignment(initialAssignment []table) (copiedAssignment []table) {
+	size := len(initialAssignment)
+
+	copiedAssignment = make([]table, size)
+
+	for i := 0; i < size; i++ {
+		copiedAssignment[i].name = initialAssignment[i].name
+		copiedAssignment[i].capacity = initialAssignment[i].capacity
+		copiedAssignment[i].minimum = initialAssignment[i].minimum
+		copiedAssignment[i].adjacent = initialAssignment[i].adjacent
+		copiedAssignment[i].pinned = initialAssignment[i].pinned
+		copiedAssignment[i].anchored = initialAssignment[i].anchored
+		copiedAssignment[i].desirability = initialAssignment[i].desirability
+		copiedAssignment[i].people = make([]Person, copiedAssignment[i].capacity)
+		copiedAssignment[i].seated = make([]bool, len(initialAssignment[i].seated))
+		copiedAssignment[i].tagged = make([]int, len(initialAssignment[i].tagged))
+		copy(copiedAssignment[i].people, initialAssignment[i].people)
+		copy(copiedAssignment[i].seated, initialAssignment[i].seated)
+		copy(copiedAssignment[i].tagged, initialAssignment[i].tagged)
+	}
+
+	return copiedAssignment
+}
+
+// loadAssignment builds an assignment from a JSON list of the names sat at each table (see seatNames)
+func loadAssignment(assignmentRaw []byte, people []Person, tables []table) (assignment []table, err error) {
+	var names [][]string
+	err = json.Unmarshal(assignmentRaw, &names)
+	if err != nil {
+		return nil, err
+	}
+	return seatNames(names, people, tables)
+}
+
+// seatNames builds an assignment from the names sat at each table, checking that it seats every person exactly once -
+// any seats not listed are left empty. Anyone already pinned to a table must be listed at that table
+func seatNames(names [][]string, people []Person, tables []table) (assignment []table, err error) {
+	if len(names) != len(tables) {
+		return nil, fmt.Errorf("assignment has %d tables but the problem has %d", len(names), len(tables))
+	}
+
+	peopleByName := make(map[string]Person)
+	for _, person := range people {
+		peopleByName[person.Name] = person
+	}
+	pinnedTo := make(map[string]int)
+	for i, table := range tables {
+		for _, person := range table.people[:table.pinned] {
+			pinnedTo[person.Name] = i
+		}
+	}
+
+	assignment = copyAssignment(tables)
+	seated := make(map[string]bool)
+	for i, tableNames := range names {
+		if len(tableNames) > assignment[i].capacity {
+			return nil, fmt.Errorf("table %d seats %d people but has capacity %d", i, len(tableNames), assignment[i].capacity)
+		}
+		for j := assignment[i].pinned; j < assignment[i].capacity; j++ {
+			assignment[i].people[j] = Person{empty: true}
+		}
+		seat := assignment[i].pinned
+		for _, name := range tableNames {
+			person, exists := peopleByName[name]
+			if !exists {
+				return nil, fmt.Errorf("%s is sat at table %d but is not in the problem", name, i)
+			}
+			if seated[name] {
+				return nil, fmt.Errorf("%s is sat more than once", name)
+			}
+			seated[name] = true
+			if tableNo, pinned := pinnedTo[name]; pinned {
+				if tableNo != i {
+					return nil, fmt.Errorf("%s is sat at table %d but is pinned to table %d", name, i, tableNo)
+				}
+				continue
+			}
+			assignment[i].people[seat] = person
+			assignment[i].seat(person)
+			seat++
+		}
+	}
+	if len(seated) != len(people) {
+		return nil, fmt.Errorf("assignment seats %d people but the problem has %d", len(seated), len(people))
+	}
+	for i, table := range assignment {
+		if occupied(table) < table.minimum {
+			return nil, fmt.Errorf("table %d seats %d people but must seat at least %d", i, occupied(table), table.minimum)
+		}
+	}
+	return assignment, nil
+}
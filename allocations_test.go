@@ -0,0 +1,1078 @@
+package allocations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// the problem sizes the benchmarks are run at
+var benchmarkSizes = []int{100, 500}
+
+// syntheticProblem returns a problem with the given number of people, each preferring three others with weights of 1 to
+// 3, sat at tables of 10. The same size always gives the same problem
+func syntheticProblem(size int) Problem {
+	rng := rand.New(rand.NewSource(1))
+	people := make([]Person, size)
+	for i := range people {
+		people[i].Name = fmt.Sprintf("P%d", i)
+	}
+	for i := range people {
+		for _, other := range rng.Perm(size)[:3] {
+			if other != i {
+				people[i].Preferences = append(people[i].Preferences, Preference{Name: people[other].Name, Weight: float64(1 + rng.Intn(3))})
+			}
+		}
+	}
+	return Problem{People: people, Tables: EqualTables(size, 10)}
+}
+
+// seatRandomly returns the problem's people sat randomly at its tables, with the scoring solve would use for it under
+// the given configuration
+func seatRandomly(p Problem, cfg Config) ([]table, scoring) {
+	tables, err := newTables(p)
+	if err != nil {
+		panic(err)
+	}
+	s, err := newScoring(p, tables, cfg, nil)
+	if err != nil {
+		panic(err)
+	}
+	p.People = indexPeople(p.People)
+	unpinned, err := pinPeople(p, tables)
+	if err != nil {
+		panic(err)
+	}
+	return randomInitialisation(rand.New(rand.NewSource(1)), unpinned, tables, s.forbidden), s
+}
+
+// scoringFor returns the scoring solve would use for the problem under the given configuration
+func scoringFor(p Problem, cfg Config) scoring {
+	tables, err := newTables(p)
+	if err != nil {
+		panic(err)
+	}
+	s, err := newScoring(p, tables, cfg, nil)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func BenchmarkCostFunction(b *testing.B) {
+	for _, mode := range []string{"sum", "count", "hybrid", "maximin"} {
+		for _, size := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", mode, size), func(b *testing.B) {
+				o, err := objectiveFor(mode)
+				if err != nil {
+					b.Fatal(err)
+				}
+				assignment, s := seatRandomly(syntheticProblem(size), Config{Mode: mode})
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					o.cost(assignment, s)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkTableParts is the cost of the one table a swap changes, as the annealers work it out
+func BenchmarkTableParts(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			assignment, s := seatRandomly(syntheticProblem(size), Config{})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tableParts(assignment, i%len(assignment), s)
+			}
+		})
+	}
+}
+
+// BenchmarkNeighbour makes a neighbouring solution and undoes it again, as happens when a neighbour is rejected
+func BenchmarkNeighbour(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			assignment, _ := seatRandomly(syntheticProblem(size), Config{})
+			movable := movableTables(assignment)
+			rng := rand.New(rand.NewSource(1))
+			swaps := make([]swap, 0, 1)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				swaps = getNeighbour(rng, assignment, movable, 1, 0, swaps[:0])
+				for j := len(swaps) - 1; j >= 0; j-- {
+					undoSwap(assignment, swaps[j])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkNeighbourByCopy makes each neighbour from a fresh copy of the solution, as the annealers did before making
+// them in place, to compare the allocations with BenchmarkNeighbour (run with -benchmem)
+func BenchmarkNeighbourByCopy(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			assignment, _ := seatRandomly(syntheticProblem(size), Config{})
+			movable := movableTables(assignment)
+			rng := rand.New(rand.NewSource(1))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				getNeighbour(rng, copyAssignment(assignment), movable, 1, 0, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkCopyAssignment(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			assignment, _ := seatRandomly(syntheticProblem(size), Config{})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				copyAssignment(assignment)
+			}
+		})
+	}
+}
+
+// BenchmarkInternalIterator is one annealer's temperature step of 1000 iterations, each making a neighbour and
+// deciding whether to keep it
+func BenchmarkInternalIterator(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			assignment, s := seatRandomly(syntheticProblem(size), Config{})
+			movable := movableTables(assignment)
+			rng := rand.New(rand.NewSource(1))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				assignment, _, _, _ = annealerInternalIterator(context.Background(), rng, assignment, movable, s, sumObjective{}, 0.1, 1000, 1, 0)
+			}
+		})
+	}
+}
+
+// benchmarkConfig is a short run with the command's defaults otherwise
+var benchmarkConfig = Config{
+	AnnealConfig: AnnealConfig{BaseTemperature: 1, FinalTemperature: 0.001, CoolingRate: 0.5, InternalIterations: 1000, SwapCount: 1, ConcurrentAnnealers: 6},
+	Mode:         "sum",
+	Seed:         1,
+}
+
+func BenchmarkSolve(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			p := syntheticProblem(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := Solve(context.Background(), p, benchmarkConfig)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Example_sumFunction() {
+	prefers := func(name string, others ...string) Person {
+		person := Person{Name: name}
+		for _, other := range others {
+			person.Preferences = append(person.Preferences, Preference{Name: other, Weight: 1})
+		}
+		return person
+	}
+	tests := []struct {
+		name   string
+		people []Person
+	}{
+		{"one-sided", []Person{prefers("A", "B"), prefers("B"), prefers("C"), prefers("D")}},
+		{"mutual", []Person{prefers("A", "B"), prefers("B", "A"), prefers("C"), prefers("D")}},
+		{"at another table", []Person{prefers("A", "C"), prefers("B"), prefers("C"), prefers("D")}},
+		{"nobody by that name", []Person{prefers("A", "E"), prefers("B"), prefers("C"), prefers("D")}},
+		{"themselves", []Person{prefers("A", "A"), prefers("B"), prefers("C"), prefers("D")}},
+		{"themselves and another", []Person{prefers("A", "A", "B"), prefers("B"), prefers("C"), prefers("D")}},
+	}
+	for _, test := range tests {
+		p := Problem{People: test.people, Tables: []TableSpec{{Max: 2}, {Max: 2}}}
+		s := scoringFor(p, Config{})
+		fmt.Printf("%s: %g", test.name, sumFunction(seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}}), s))
+		fmt.Println()
+	}
+	// Output:
+	// one-sided: 1
+	// mutual: 2
+	// at another table: 0
+	// nobody by that name: 0
+	// themselves: 0
+	// themselves and another: 1
+}
+
+// Example_tagPreferences scores someone who prefers both anyone tagged "eng" and D by name, sat with different people -
+// the tag and the name each count, including when D is also tagged
+func Example_tagPreferences() {
+	people := []Person{
+		{Name: "A", Preferences: []Preference{{Name: "#eng", Weight: 1}, {Name: "D", Weight: 1}}},
+		{Name: "B", Tags: []string{"eng"}},
+		{Name: "C", Tags: []string{"eng"}},
+		{Name: "D"},
+		{Name: "E"},
+		{Name: "F"},
+	}
+	p := Problem{People: people, Tables: []TableSpec{{Max: 3}, {Max: 3}}}
+	s := scoringFor(p, Config{})
+	for _, test := range []struct {
+		name   string
+		tables [][]string
+	}{
+		{"tagged and named", [][]string{{"A", "B", "D"}, {"C", "E", "F"}}},
+		{"both tagged", [][]string{{"A", "B", "C"}, {"D", "E", "F"}}},
+		{"only named", [][]string{{"A", "D", "E"}, {"B", "C", "F"}}},
+		{"neither", [][]string{{"A", "E", "F"}, {"B", "C", "D"}}},
+	} {
+		fmt.Printf("%s: %g", test.name, sumFunction(seatProblem(p, test.tables), s))
+		fmt.Println()
+	}
+
+	people[3].Tags = []string{"eng"}
+	s = scoringFor(p, Config{})
+	fmt.Printf("named and tagged: %g", sumFunction(seatProblem(p, [][]string{{"A", "D", "E"}, {"B", "C", "F"}}), s))
+	fmt.Println()
+	// Output:
+	// tagged and named: 2
+	// both tagged: 2
+	// only named: 1
+	// neither: 0
+	// named and tagged: 2
+}
+
+func Example_randomInitialisation() {
+	p := syntheticProblem(30)
+	tables, err := newTables(p)
+	if err != nil {
+		panic(err)
+	}
+	people := indexPeople(p.People)
+	seat := func(seed int64) [][]string {
+		assignment := randomInitialisation(rand.New(rand.NewSource(seed)), people, copyAssignment(tables), nil)
+		return Solution{Assignment: assignment}.Tables()
+	}
+	fmt.Println("same seed:", reflect.DeepEqual(seat(7), seat(7)))
+	fmt.Println("another seed:", reflect.DeepEqual(seat(7), seat(8)))
+
+	solve := func() [][]string {
+		solution, err := Solve(context.Background(), p, benchmarkConfig)
+		if err != nil {
+			panic(err)
+		}
+		return solution.Tables()
+	}
+	fmt.Println("solved with the same seed:", reflect.DeepEqual(solve(), solve()))
+	// Output:
+	// same seed: true
+	// another seed: false
+	// solved with the same seed: true
+}
+
+// Example_randomInitialisationOrder seats people at tables with seats to spare, printing the slice of people passed in
+// before and after - it's shuffled (and the empty seats added) in a copy, so the caller's slice is left as it was
+func Example_randomInitialisationOrder() {
+	p := Problem{
+		People: []Person{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}, {Name: "E"}},
+		Tables: []TableSpec{{Max: 4}, {Max: 4}},
+	}
+	tables, err := newTables(p)
+	if err != nil {
+		panic(err)
+	}
+	people := indexPeople(p.People)
+	names := func() (names []string) {
+		for _, person := range people {
+			names = append(names, person.Name)
+		}
+		return names
+	}
+	fmt.Println("before:", names())
+	assignment := randomInitialisation(rand.New(rand.NewSource(1)), people, tables, nil)
+	fmt.Println("seated:", Solution{Assignment: assignment}.Tables())
+	fmt.Println("after:", names(), len(people))
+	// Output:
+	// before: [A B C D E]
+	// seated: [[C E] [A B D]]
+	// after: [A B C D E] 5
+}
+
+// Example_incrementalCost makes many random neighbours of a problem using every part of the cost, keeping about half,
+// and checks that the cost worked out a table at a time (as the annealers do) matches the cost worked out from scratch.
+// The module's Go version is older than native fuzzing, so the neighbours are drawn from a seeded rng instead
+func Example_incrementalCost() {
+	p := syntheticProblem(40)
+	p.Tables = append(p.Tables, TableSpec{Min: 2, Max: 6, Desirability: 1})
+	p.AdjacentTables = [][2]int{{0, 1}, {1, 4}}
+	p.PlusOnes = []PlusOne{{PersonOne: "P0", PersonTwo: "P1"}}
+	p.Apart = [][2]string{{"P2", "P3"}}
+	p.Previous = [][]string{{"P4", "P5", "P6"}}
+	for i := range p.People {
+		p.People[i].VIP = float64(i % 3)
+		p.People[i].Tags = []string{fmt.Sprint("team", i%4)}
+		p.People[i].Attributes = map[string]string{"department": fmt.Sprint(i % 3)}
+		if i%5 == 0 {
+			p.People[i].Preferences = append(p.People[i].Preferences, Preference{Name: "#team1", Weight: 1}, Preference{Name: "P7", Weight: -2})
+			p.People[i].Avoid = []string{"P8"}
+		}
+	}
+	cfg := Config{
+		ObjectiveWeights:      ObjectiveWeights{PreferenceWeight: 2, AdjacentTableCredit: 0.5, AvoidPenalty: 3, LonelyPenalty: 1, MutualBonus: 1, DesirabilityWeight: 0.5, StabilityWeight: 1, BalanceWeight: 1},
+		MinSatisfiedPerPerson: 1,
+		Balance:               "department",
+	}
+
+	for _, mode := range []string{"sum", "count", "hybrid"} {
+		o, err := objectiveFor(mode)
+		if err != nil {
+			panic(err)
+		}
+		partsCost := o.(partsObjective)
+		assignment, s := seatRandomly(p, cfg)
+		movable := movableTables(assignment)
+		highest := getHighestCost(assignment, s)
+		parts := make([]costParts, len(assignment))
+		var total costParts
+		for tableNo := range assignment {
+			parts[tableNo] = partsCost.tableParts(assignment, tableNo, s)
+			total = total.add(parts[tableNo])
+		}
+
+		rng := rand.New(rand.NewSource(1))
+		mismatches := 0
+		var swaps []swap
+		var affected []int
+		for i := 0; i < 5000; i++ {
+			swaps = getNeighbour(rng, assignment, movable, 1+rng.Intn(3), 0.3, swaps[:0])
+			affected = affectedTables(assignment, swaps, affected[:0])
+			candidateTotal := total
+			candidateParts := make([]costParts, len(affected))
+			for j, tableNo := range affected {
+				candidateParts[j] = partsCost.tableParts(assignment, tableNo, s)
+				candidateTotal = candidateTotal.sub(parts[tableNo]).add(candidateParts[j])
+			}
+			if math.Abs(partsCost.fromParts(candidateTotal, highest)-o.cost(assignment, s)) > 1e-6 {
+				mismatches++
+			}
+			if rng.Intn(2) == 0 {
+				keepParts(parts, affected, candidateParts)
+				total = candidateTotal
+				continue
+			}
+			for j := len(swaps) - 1; j >= 0; j-- {
+				undoSwap(assignment, swaps[j])
+			}
+		}
+		fmt.Printf("%s: %d mismatches", mode, mismatches)
+		fmt.Println()
+	}
+	// Output:
+	// sum: 0 mismatches
+	// count: 0 mismatches
+	// hybrid: 0 mismatches
+}
+
+// BenchmarkAnnealers solves with the six annealers run at once, and with them limited to one processor between them
+// (as if they were run one after another), to show the wall-clock time saved by running them in parallel
+func BenchmarkAnnealers(b *testing.B) {
+	p := syntheticProblem(500)
+	for _, run := range []struct {
+		name       string
+		processors int
+	}{{"parallel", runtime.GOMAXPROCS(0)}, {"serial", 1}} {
+		b.Run(run.name, func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(run.processors))
+			for i := 0; i < b.N; i++ {
+				_, err := Solve(context.Background(), p, benchmarkConfig)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Example_penalty seats a problem with hard constraints and soft terms at random many times, and checks that, with the
+// default penalty, every seating that meets the hard constraints costs more than every seating that breaks any
+func Example_penalty() {
+	p := syntheticProblem(20)
+	p.PlusOnes = []PlusOne{{PersonOne: "P0", PersonTwo: "P1"}}
+	p.Apart = [][2]string{{"P2", "P3"}, {"P4", "P5"}}
+	p.Groups = [][]string{{"P6", "P7", "P8"}}
+	p.Tables[0].Desirability = 2
+	for i := range p.People {
+		p.People[i].VIP = float64(i % 4)
+	}
+	cfg := Config{ObjectiveWeights: ObjectiveWeights{AdjacentTableCredit: 0.5, LonelyPenalty: 1, MutualBonus: 2, DesirabilityWeight: 1}}
+
+	for _, mode := range []string{"sum", "count", "hybrid", "maximin"} {
+		o, err := objectiveFor(mode)
+		if err != nil {
+			panic(err)
+		}
+		tables, err := newTables(p)
+		if err != nil {
+			panic(err)
+		}
+		cfg.Mode = mode
+		s := scoringFor(p, cfg)
+		people := indexPeople(p.People)
+		rng := rand.New(rand.NewSource(1))
+		feasible := 0
+		worstFeasible, bestInfeasible := math.Inf(1), math.Inf(-1)
+		for i := 0; i < 2000; i++ {
+			assignment := randomInitialisation(rng, people, copyAssignment(tables), nil)
+			cost := o.cost(assignment, s)
+			if getCostParts(assignment, s).penalty > 0 {
+				bestInfeasible = math.Max(bestInfeasible, cost)
+			} else {
+				feasible++
+				worstFeasible = math.Min(worstFeasible, cost)
+			}
+		}
+		fmt.Printf("%s: feasible beats infeasible: %t", mode, feasible > 0 && worstFeasible > bestInfeasible)
+		fmt.Println()
+	}
+	// Output:
+	// sum: feasible beats infeasible: true
+	// count: feasible beats infeasible: true
+	// hybrid: feasible beats infeasible: true
+	// maximin: feasible beats infeasible: true
+}
+
+// Example_optimal solves six people at two tables of three, where the best seatings (such as a, b and c with d, e and
+// f) satisfy six preferences - working through the others by hand, none satisfy more
+func Example_optimal() {
+	problem, err := LoadProblem(strings.NewReader(`{"people":[
+		{"name":"a","preferences":["b","c"]},
+		{"name":"b","preferences":["a"]},
+		{"name":"c","preferences":["d"]},
+		{"name":"d","preferences":["c","e"]},
+		{"name":"e","preferences":["f"]},
+		{"name":"f","preferences":["e","a"]}
+	],"tables":[3,3]}`))
+	if err != nil {
+		panic(err)
+	}
+	cfg := benchmarkConfig
+	cfg.Solver = OptimalSolver
+	solution, err := Solve(context.Background(), problem, cfg)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("optimal:", solution.Cost)
+	cfg.Solver = AnnealSolver
+	solution, err = Solve(context.Background(), problem, cfg)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("annealed:", solution.Cost)
+
+	cfg.Solver = OptimalSolver
+	_, err = Solve(context.Background(), syntheticProblem(optimalMaxPeople+1), cfg)
+	fmt.Println(err)
+	// Output:
+	// optimal: 6
+	// annealed: 6
+	// the optimal solver tries every way of seating people, so can seat at most 12 (besides anyone pinned), but there are 13
+}
+
+// Example_balance solves a problem where everyone prefers people from their own department, with and without
+// balancing the departments, and compares how far the tables are from half of each
+func Example_balance() {
+	p := Problem{Tables: EqualTables(24, 6)}
+	for i := 0; i < 24; i++ {
+		department := fmt.Sprint(i % 2)
+		person := Person{Name: fmt.Sprintf("P%d", i), Attributes: map[string]string{"department": department}}
+		for _, other := range []int{(i + 2) % 24, (i + 4) % 24} {
+			person.Preferences = append(person.Preferences, Preference{Name: fmt.Sprintf("P%d", other), Weight: 1})
+		}
+		p.People = append(p.People, person)
+	}
+	unbalanced := func(cfg Config) (away int) {
+		solution, err := Solve(context.Background(), p, cfg)
+		if err != nil {
+			panic(err)
+		}
+		for _, table := range solution.Assignment {
+			zeros := 0
+			for _, person := range table.people {
+				if !person.empty && person.Attributes["department"] == "0" {
+					zeros++
+				}
+			}
+			if zeros > 3 {
+				away += zeros - 3
+			} else {
+				away += 3 - zeros
+			}
+		}
+		return away
+	}
+
+	cfg := benchmarkConfig
+	without := unbalanced(cfg)
+	cfg.Balance = "department"
+	cfg.BalanceWeight = 5
+	with := unbalanced(cfg)
+	fmt.Println("people away from an even mix without balancing:", without)
+	fmt.Println("people away from an even mix when balancing:", with)
+	// Output:
+	// people away from an even mix without balancing: 12
+	// people away from an even mix when balancing: 0
+}
+
+// Example_countFunction checks that someone sat only with a person they'd rather not be sat with isn't counted as
+// satisfied
+func Example_countFunction() {
+	p := Problem{
+		People: []Person{
+			{Name: "A", Preferences: []Preference{{Name: "B", Weight: -5}}},
+			{Name: "B", Preferences: []Preference{{Name: "A", Weight: 1}}},
+			{Name: "C"},
+			{Name: "D"},
+		},
+		Tables: []TableSpec{{Max: 2}, {Max: 2}},
+	}
+	assignment := seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}})
+	s := scoringFor(p, Config{})
+	fmt.Println("count:", countFunction(assignment, s))
+	fmt.Println("sum:", sumFunction(assignment, s))
+	// Output:
+	// count: 1
+	// sum: -4
+}
+
+// Example_maximinObjective solves a problem where the seating with the highest sum (A and B together, for both of their
+// heaviest preferences) leaves C and D with nothing, so maximin gives up some of the sum to satisfy everyone
+func Example_maximinObjective() {
+	p := Problem{
+		People: []Person{
+			{Name: "A", Preferences: []Preference{{Name: "B", Weight: 5}, {Name: "C", Weight: 1}}},
+			{Name: "B", Preferences: []Preference{{Name: "A", Weight: 5}, {Name: "D", Weight: 1}}},
+			{Name: "C", Preferences: []Preference{{Name: "A", Weight: 1}}},
+			{Name: "D", Preferences: []Preference{{Name: "B", Weight: 1}}},
+		},
+		Tables: []TableSpec{{Max: 2}, {Max: 2}},
+	}
+	cfg := benchmarkConfig
+	cfg.Solver = OptimalSolver
+	for _, mode := range []string{"sum", "maximin"} {
+		cfg.Mode = mode
+		solution, err := Solve(context.Background(), p, cfg)
+		if err != nil {
+			panic(err)
+		}
+		worst, _ := getWorstOff(solution.Assignment)
+		fmt.Printf("%s: %v, sum %g, worst off satisfied %d", mode, solution.Tables(), sumFunction(solution.Assignment, scoringFor(p, Config{})), worst)
+		fmt.Println()
+	}
+	// Output:
+	// sum: [[A B] [C D]], sum 10, worst off satisfied 0
+	// maximin: [[A C] [B D]], sum 4, worst off satisfied 1
+}
+
+// Example_getSatisfaction works out the satisfaction of someone who prefers B but would rather avoid C (as a negative
+// weight from a matrix), which stays between 0 and 100% whoever they're sat with
+func Example_getSatisfaction() {
+	p := Problem{
+		People: []Person{
+			{Name: "A", Preferences: []Preference{{Name: "B", Weight: 1}, {Name: "C", Weight: -5}}},
+			{Name: "B"}, {Name: "C"}, {Name: "D"},
+		},
+		Tables: []TableSpec{{Max: 3}, {Max: 3}},
+	}
+	for _, tables := range [][][]string{{{"A", "B"}, {"C", "D"}}, {{"A", "C"}, {"B", "D"}}, {{"A", "B", "C"}, {"D"}}, {{"A", "D"}, {"B", "C"}}} {
+		fmt.Printf("%v: %g%%", tables, getSatisfaction(seatProblem(p, tables)))
+		fmt.Println()
+	}
+	// Output:
+	// [[A B] [C D]]: 100%
+	// [[A C] [B D]]: 0%
+	// [[A B C] [D]]: 100%
+	// [[A D] [B C]]: 0%
+}
+
+// Example_pinPeople checks that a minimum only the people pinned elsewhere could make up is an error, rather than a
+// panic when seating people
+func Example_pinPeople() {
+	p := Problem{
+		People: []Person{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}},
+		Tables: []TableSpec{{Min: 3, Max: 3}, {Max: 3}},
+		Pinned: map[string]int{"A": 1, "B": 1, "C": 1},
+	}
+	_, err := Solve(context.Background(), p, benchmarkConfig)
+	fmt.Println(err)
+	// Output:
+	// the tables' minimums need 3 more people than are pinned to them, but only 1 people aren't pinned
+}
+
+// BenchmarkCandidates solves with each annealer trying one neighbour at a time, and with several made at once by its
+// candidate workers, reporting the cost found alongside the time taken
+func BenchmarkCandidates(b *testing.B) {
+	p := syntheticProblem(500)
+	for _, candidates := range []int{1, 4} {
+		b.Run(fmt.Sprintf("candidates=%d", candidates), func(b *testing.B) {
+			cfg := benchmarkConfig
+			cfg.CandidateWorkers = candidates
+			b.ReportAllocs()
+			cost := 0.0
+			for i := 0; i < b.N; i++ {
+				solution, err := Solve(context.Background(), p, cfg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				cost += solution.Cost
+			}
+			b.ReportMetric(cost/float64(b.N), "cost")
+		})
+	}
+}
+
+func Example_swapsAt() {
+	cfg := AnnealConfig{BaseTemperature: 1, FinalTemperature: 0.001, CoolingRate: 0.5, SwapCount: 5, SwapSchedule: DecreasingSwaps}
+	for _, temperature := range []float64{2, 1, 0.1, 0.01, 0.001} {
+		fmt.Printf("%g: %d", temperature, swapsAt(temperature, cfg))
+		fmt.Println()
+	}
+	cfg.SwapSchedule = FixedSwaps
+	fmt.Println("fixed at the final temperature:", swapsAt(cfg.FinalTemperature, cfg))
+	// Output:
+	// 2: 5
+	// 1: 5
+	// 0.1: 4
+	// 0.01: 2
+	// 0.001: 1
+	// fixed at the final temperature: 5
+}
+
+// Example_getDesirability solves a problem with nothing but VIPs and a desirable table, and counts the VIPs sat at it
+func Example_getDesirability() {
+	p := Problem{Tables: []TableSpec{{Max: 4}, {Max: 4, Desirability: 1}}}
+	for i := 0; i < 8; i++ {
+		p.People = append(p.People, Person{Name: fmt.Sprintf("P%d", i), VIP: float64(i % 2)})
+	}
+	for _, weight := range []float64{0, 1} {
+		cfg := benchmarkConfig
+		cfg.DesirabilityWeight = weight
+		solution, err := Solve(context.Background(), p, cfg)
+		if err != nil {
+			panic(err)
+		}
+		vips := 0
+		for _, person := range solution.Assignment[1].people {
+			if !person.empty && person.VIP > 0 {
+				vips++
+			}
+		}
+		fmt.Printf("weight %g: %d VIPs at the desirable table, cost %g", weight, vips, solution.Cost)
+		fmt.Println()
+	}
+	// Output:
+	// weight 0: 3 VIPs at the desirable table, cost 0
+	// weight 1: 4 VIPs at the desirable table, cost 4
+}
+
+// tableState is what a swap changes about a table, to compare before and after one
+type tableState struct {
+	people []Person
+	seated []bool
+	tagged []int
+	parts  costParts
+}
+
+// tableStates returns the state of each table, copied so that later swaps don't change it
+func tableStates(assignment []table, s scoring) []tableState {
+	states := make([]tableState, len(assignment))
+	for i, t := range assignment {
+		states[i] = tableState{
+			people: append([]Person(nil), t.people...),
+			seated: append([]bool(nil), t.seated...),
+			tagged: append([]int(nil), t.tagged...),
+			parts:  tableParts(assignment, i, s),
+		}
+	}
+	return states
+}
+
+// Example_undoSwap swaps two people and undoes it, checking that the tables' people, who is marked as sat at each,
+// their tags and their parts of the cost are as they were
+func Example_undoSwap() {
+	p := syntheticProblem(20)
+	for i := range p.People {
+		p.People[i].Tags = []string{fmt.Sprint("team", i%3)}
+	}
+	assignment, s := seatRandomly(p, Config{ObjectiveWeights: ObjectiveWeights{MutualBonus: 1, LonelyPenalty: 1}})
+	before := tableStates(assignment, s)
+	move := swap{tableOne: 0, seatOne: 3, tableTwo: 1, seatTwo: 7}
+
+	applySwap(assignment, move)
+	fmt.Println("changed by the swap:", !reflect.DeepEqual(before, tableStates(assignment, s)))
+	fmt.Println("swapped people marked as seated:", assignment[1].has(before[0].people[3].id), assignment[0].has(before[1].people[7].id))
+	undoSwap(assignment, move)
+	fmt.Println("unchanged after undoing it:", reflect.DeepEqual(before, tableStates(assignment, s)))
+	// Output:
+	// changed by the swap: true
+	// swapped people marked as seated: true true
+	// unchanged after undoing it: true
+}
+
+func ExamplePreference_UnmarshalJSON() {
+	var person Person
+	err := json.Unmarshal([]byte(`{"name":"A","preferences":["B",{"name":"C","weight":5},{"name":"D"}]}`), &person)
+	if err != nil {
+		panic(err)
+	}
+	for _, preference := range person.Preferences {
+		fmt.Println(preference.Name, preference.Weight)
+	}
+	// Output:
+	// B 1
+	// C 5
+	// D 1
+}
+
+// Example_weightedPreferences solves a problem where there's only room for one of A's two preferences, so the heavier
+// one should win - however the other is listed first
+func Example_weightedPreferences() {
+	for _, preferences := range []string{`["B",{"name":"C","weight":5}]`, `[{"name":"B","weight":5},"C"]`} {
+		p, err := LoadProblem(strings.NewReader(`{"people":[{"name":"A","preferences":` + preferences + `},{"name":"B"},{"name":"C"},{"name":"D"}],"tables":[2,2]}`))
+		if err != nil {
+			panic(err)
+		}
+		solution, err := Solve(context.Background(), p, benchmarkConfig)
+		if err != nil {
+			panic(err)
+		}
+		for _, names := range solution.Tables() {
+			if names[0] == "A" || names[1] == "A" {
+				fmt.Println(names, solution.Cost)
+			}
+		}
+	}
+	// Output:
+	// [A C] 5
+	// [A B] 5
+}
+
+// Example_applySwap moves people into an empty seat at another table, with the empty seat as either side of the swap,
+// and then undoes each move, printing how many are sat at each table and the cost (both from scratch and as the sum of
+// each table's parts) as it goes
+func Example_applySwap() {
+	p := Problem{
+		People: []Person{{Name: "A", Preferences: []Preference{{Name: "C", Weight: 1}}}, {Name: "B"}, {Name: "C"}},
+		Tables: []TableSpec{{Max: 2}, {Max: 2}},
+	}
+	assignment := seatProblem(p, [][]string{{"A", "B"}, {"C"}})
+	s := scoringFor(p, Config{})
+	show := func(step string) {
+		var parts costParts
+		for tableNo := range assignment {
+			parts = parts.add(tableParts(assignment, tableNo, s))
+		}
+		fmt.Printf("%s: %v at each table, cost %g (%g from the parts)", step, []int{occupied(assignment[0]), occupied(assignment[1])}, sumFunction(assignment, s), sumOfParts(parts, 0))
+		fmt.Println()
+	}
+	show("start")
+
+	// the empty seat is the second of the swap, so A moves into it
+	into := swap{tableOne: 0, seatOne: 0, tableTwo: 1, seatTwo: 1}
+	applySwap(assignment, into)
+	show("A moved into the empty seat")
+	undoSwap(assignment, into)
+	show("undone")
+
+	// the empty seat is the first of the swap, so B moves from the other seat into it
+	from := swap{tableOne: 1, seatOne: 1, tableTwo: 0, seatTwo: 1}
+	applySwap(assignment, from)
+	show("B moved from table 0 to the empty seat")
+	undoSwap(assignment, from)
+	show("undone")
+	// Output:
+	// start: [2 1] at each table, cost 0 (0 from the parts)
+	// A moved into the empty seat: [1 2] at each table, cost 1 (1 from the parts)
+	// undone: [2 1] at each table, cost 0 (0 from the parts)
+	// B moved from table 0 to the empty seat: [1 2] at each table, cost 0 (0 from the parts)
+	// undone: [2 1] at each table, cost 0 (0 from the parts)
+}
+
+// Example_getNeighbour makes neighbours of a seating where one table's people are deliberately short of its capacity
+// and another has an empty seat, checking that only the people actually sat at each table are marked as sat there -
+// with no seat beyond the people there picked, and no phantom person (as a zero-value one would be the first person)
+func Example_getNeighbour() {
+	p := Problem{
+		People: []Person{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}, {Name: "E"}},
+		Tables: []TableSpec{{Max: 3}, {Max: 3}, {Max: 3}},
+	}
+	assignment := seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}, {"E"}})
+	assignment[0].people = assignment[0].people[:2]
+	rng := rand.New(rand.NewSource(1))
+	movable := movableTables(assignment)
+	phantoms := 0
+	for i := 0; i < 1000; i++ {
+		getNeighbour(rng, assignment, movable, 1, 0.5, nil)
+		for _, t := range assignment {
+			seated := 0
+			for id, isSeated := range t.seated {
+				if isSeated {
+					seated++
+					if !containsPerson(t.people, id) {
+						phantoms++
+					}
+				}
+			}
+			if seated != occupied(t) {
+				phantoms++
+			}
+		}
+	}
+	fmt.Println("people at each table:", len(assignment[0].people), len(assignment[1].people), len(assignment[2].people))
+	fmt.Println("sat:", occupied(assignment[0])+occupied(assignment[1])+occupied(assignment[2]))
+	fmt.Println("phantoms:", phantoms)
+	// Output:
+	// people at each table: 2 3 3
+	// sat: 5
+	// phantoms: 0
+}
+
+// containsPerson reports whether the person with the given index is among the people
+func containsPerson(people []Person, id int) bool {
+	for _, person := range people {
+		if !person.empty && person.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func Example_acceptanceProbability() {
+	tests := []struct {
+		name                          string
+		oldCost, newCost, temperature float64
+	}{
+		{"huge worsening", 0, -math.MaxFloat64, 1},
+		{"huge improvement", -math.MaxFloat64, math.MaxFloat64, 1e-300},
+		{"worse at a tiny temperature", 0, -1e300, 1e-300},
+		{"worse at zero", 0, -1, 0},
+		{"better at zero", 0, 1, 0},
+		{"worse at a negative temperature", 0, -1, -1},
+		{"infinite costs", math.Inf(1), math.Inf(1), 1},
+		{"slightly worse", 0, -1, 1},
+	}
+	for _, test := range tests {
+		probability := acceptanceProbability(test.oldCost, test.newCost, test.temperature)
+		fmt.Printf("%s: %.4g (in range: %t)", test.name, probability, probability >= 0 && probability <= 1)
+		fmt.Println()
+	}
+	// Output:
+	// huge worsening: 0 (in range: true)
+	// huge improvement: 1 (in range: true)
+	// worse at a tiny temperature: 0 (in range: true)
+	// worse at zero: 0 (in range: true)
+	// better at zero: 0 (in range: true)
+	// worse at a negative temperature: 0 (in range: true)
+	// infinite costs: 0 (in range: true)
+	// slightly worse: 0.3679 (in range: true)
+}
+
+// Example_mutualBonus scores a pair who both prefer each other, and one where only one prefers the other, with a
+// mutual bonus of 3
+func Example_mutualBonus() {
+	for _, test := range []struct {
+		name string
+		b    Person
+	}{
+		{"mutual", Person{Name: "B", Preferences: []Preference{{Name: "A", Weight: 1}}}},
+		{"one-way", Person{Name: "B"}},
+	} {
+		p := Problem{
+			People: []Person{{Name: "A", Preferences: []Preference{{Name: "B", Weight: 1}}}, test.b, {Name: "C"}, {Name: "D"}},
+			Tables: []TableSpec{{Max: 2}, {Max: 2}},
+		}
+		s := scoringFor(p, Config{ObjectiveWeights: ObjectiveWeights{MutualBonus: 3}})
+		assignment := seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}})
+		fmt.Printf("%s: cost %g with %d mutual pairs together", test.name, sumFunction(assignment, s), getMutualTogether(assignment, s))
+		fmt.Println()
+	}
+	// Output:
+	// mutual: cost 5 with 1 mutual pairs together
+	// one-way: cost 1 with 0 mutual pairs together
+}
+
+// Example_lonelyPenalty scores two seatings with the same raw score, where only the second leaves someone (D) sat with
+// none of their preferences, then solves the problem with a lonely penalty
+func Example_lonelyPenalty() {
+	p := Problem{
+		People: []Person{
+			{Name: "A", Preferences: []Preference{{Name: "B", Weight: 2}, {Name: "C", Weight: 2}}},
+			{Name: "B"},
+			{Name: "C", Preferences: []Preference{{Name: "A", Weight: 2}, {Name: "D", Weight: 1}}},
+			{Name: "D", Preferences: []Preference{{Name: "C", Weight: 1}}},
+		},
+		Tables: []TableSpec{{Max: 2}, {Max: 2}},
+	}
+	cfg := benchmarkConfig
+	cfg.LonelyPenalty = 1
+	raw, penalised := scoringFor(p, Config{}), scoringFor(p, cfg)
+	for _, tables := range [][][]string{{{"A", "B"}, {"C", "D"}}, {{"A", "C"}, {"B", "D"}}} {
+		assignment := seatProblem(p, tables)
+		fmt.Printf("%v: raw %g, lonely %d, with the penalty %g", tables, sumFunction(assignment, raw), getLonely(assignment), sumFunction(assignment, penalised))
+		fmt.Println()
+	}
+
+	solution, err := Solve(context.Background(), p, cfg)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("solved:", solution.Tables())
+	// Output:
+	// [[A B] [C D]]: raw 4, lonely 0, with the penalty 4
+	// [[A C] [B D]]: raw 4, lonely 1, with the penalty 3
+	// solved: [[A B] [C D]]
+}
+
+// Example_reheating anneals a small problem with a single annealer and few iterations, so that plain cooling can get
+// stuck, with and without reheating for ten seeds. Plain cooling is given at least as many iterations in total, by
+// trying more at each of its steps to make up for the steps reheating adds
+func Example_reheating() {
+	p := syntheticProblem(12)
+	p.Tables = EqualTables(12, 4)
+	better, asGood := 0, 0
+	for seed := int64(1); seed <= 10; seed++ {
+		cfg := benchmarkConfig
+		cfg.Seed = seed
+		cfg.InternalIterations = 100
+		cfg.ConcurrentAnnealers = 1
+		cfg.ReheatAfterStall, cfg.ReheatFactor, cfg.MaxReheats = 2, 10, 5
+		reheated, err := Solve(context.Background(), p, cfg)
+		if err != nil {
+			panic(err)
+		}
+
+		plain := cfg
+		plain.ReheatAfterStall = 0
+		cooled, err := Solve(context.Background(), p, plain)
+		if err != nil {
+			panic(err)
+		}
+		plain.InternalIterations = (cfg.InternalIterations*reheated.Stats.Steps + cooled.Stats.Steps - 1) / cooled.Stats.Steps
+		cooled, err = Solve(context.Background(), p, plain)
+		if err != nil {
+			panic(err)
+		}
+
+		if reheated.Cost >= cooled.Cost {
+			asGood++
+		}
+		if reheated.Cost > cooled.Cost {
+			better++
+		}
+	}
+	fmt.Printf("reheating did at least as well for %d of 10 seeds, and better for %d", asGood, better)
+	fmt.Println()
+	// Output:
+	// reheating did at least as well for 10 of 10 seeds, and better for 4
+}
+
+// Example_cool cools from the base temperature under each schedule, as anneal does, until the final temperature is
+// reached
+func Example_cool() {
+	for _, schedule := range []struct {
+		name string
+		cfg  AnnealConfig
+	}{
+		{"geometric", AnnealConfig{BaseTemperature: 1, FinalTemperature: 0.001, CoolingSchedule: Geometric, CoolingRate: 0.5}},
+		{"linear", AnnealConfig{BaseTemperature: 1, FinalTemperature: 0.001, CoolingSchedule: Linear, CoolingSteps: 7}},
+		{"linear, with steps that aren't exact in floating point", AnnealConfig{BaseTemperature: 1, FinalTemperature: 0.3, CoolingSchedule: Linear, CoolingSteps: 3}},
+	} {
+		temperature, steps := schedule.cfg.BaseTemperature, 0
+		for temperature > schedule.cfg.FinalTemperature && steps < 1000 {
+			temperature = cool(temperature, schedule.cfg)
+			steps++
+		}
+		fmt.Printf("%s: %.4g after %d steps", schedule.name, temperature, steps)
+		fmt.Println()
+	}
+	// Output:
+	// geometric: 0.0009766 after 10 steps
+	// linear: 0.001 after 7 steps
+	// linear, with steps that aren't exact in floating point: 0.3 after 3 steps
+}
+
+// validAssignment returns what's wrong with the assignment of the problem's people, if anything: everyone must be sat
+// exactly once, with each table's record of who is sat at it matching its seats and between its minimum and capacity
+func validAssignment(p Problem, assignment []table) (problems []string) {
+	ids := personIDs(p.People)
+	sat := make(map[string]int)
+	for tableNo, t := range assignment {
+		for _, person := range t.people {
+			if !person.empty {
+				sat[person.Name]++
+			}
+		}
+		for id, seated := range t.seated {
+			if seated != containsPerson(t.people, id) {
+				problems = append(problems, fmt.Sprintf("table %d marks person %d as sat at it wrongly", tableNo, id))
+			}
+		}
+		if occupied(t) < t.minimum || len(t.people) != t.capacity {
+			problems = append(problems, fmt.Sprintf("table %d seats %d of %d", tableNo, occupied(t), t.capacity))
+		}
+	}
+	for name := range ids {
+		if sat[name] != 1 {
+			problems = append(problems, fmt.Sprintf("%s is sat %d times", name, sat[name]))
+		}
+	}
+	return problems
+}
+
+// Example_shouldExchange solves with the Metropolis criterion for exchanges, and checks that the solutions the
+// annealers had after their exchanges (the best few of which are kept) are still valid
+func Example_shouldExchange() {
+	p := syntheticProblem(30)
+	p.Tables = append(EqualTables(26, 4), TableSpec{Min: 2, Max: 4})
+	cfg := benchmarkConfig
+	cfg.Exchange = Metropolis
+	cfg.TopK = 5
+	solution, err := Solve(context.Background(), p, cfg)
+	if err != nil {
+		panic(err)
+	}
+	exchanges := 0
+	for _, n := range solution.Stats.Exchanges {
+		exchanges += n
+	}
+	fmt.Println("exchanged:", exchanges > 0)
+	fmt.Println("best:", validAssignment(p, solution.Assignment))
+	for _, top := range solution.Top {
+		fmt.Println("top:", validAssignment(p, top.Assignment))
+	}
+	// Output:
+	// exchanged: true
+	// best: []
+	// top: []
+	// top: []
+	// top: []
+	// top: []
+	// top: []
+}
@@ -0,0 +1,263 @@
+package allocations
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// the most people listed by name for each group in the analysis, so that large groups don't fill the screen
+const analysisNamesShown = 5
+
+// preferenceComponents returns the groups of people linked to each other by preferences (in either direction, where a
+// tag links a person to everyone with it), largest first. The people must have been indexed
+func preferenceComponents(people []Person) [][]Person {
+	// union-find over people's indexes, where each person starts in their own component
+	parent := make([]int, len(people))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	tagged := make(map[int][]int)
+	for _, person := range people {
+		for _, tag := range person.tagIDs {
+			tagged[tag] = append(tagged[tag], person.id)
+		}
+	}
+	for _, person := range people {
+		for _, preference := range person.Preferences {
+			linked := []int{preference.id}
+			if preference.isTag {
+				linked = tagged[preference.id]
+			}
+			for _, id := range linked {
+				if id >= 0 {
+					parent[find(id)] = find(person.id)
+				}
+			}
+		}
+	}
+
+	byRoot := make(map[int][]Person)
+	var roots []int
+	for _, person := range people {
+		root := find(person.id)
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], person)
+	}
+	components := make([][]Person, len(roots))
+	for i, root := range roots {
+		components[i] = byRoot[root]
+	}
+	sort.SliceStable(components, func(i, j int) bool {
+		return len(components[i]) > len(components[j])
+	})
+	return components
+}
+
+// fitComponents returns the index of a table for each component (as given by preferenceComponents) to be sat at
+// entirely, with no two components sharing a table, or nil if they can't all be fitted that way. People linked to
+// nobody are left out, as they can be sat anywhere. Tables are given to the largest components first, each taking the
+// smallest table it fits at
+func fitComponents(components [][]Person, tables []table) []int {
+	used := make([]bool, len(tables))
+	fitted := make([]int, len(components))
+	for i, component := range components {
+		fitted[i] = -1
+		if len(component) == 1 {
+			continue
+		}
+		for tableNo, table := range tables {
+			if !used[tableNo] && table.capacity >= len(component) && (fitted[i] == -1 || table.capacity < tables[fitted[i]].capacity) {
+				fitted[i] = tableNo
+			}
+		}
+		if fitted[i] == -1 {
+			return nil
+		}
+		used[fitted[i]] = true
+	}
+	return fitted
+}
+
+// preferenceBound returns an upper bound on the weight of preferences that can be satisfied at the same table, as
+// nobody can be sat with more people than the largest table has other seats for - so each person can at most have
+// that many of their heaviest preferences satisfied (where a tag counts once for everyone else with it). The people
+// must have been indexed
+func preferenceBound(people []Person, tables []table) float64 {
+	largest := largestCapacity(tables)
+	tagged := make(map[int]int)
+	for _, person := range people {
+		for _, tag := range person.tagIDs {
+			tagged[tag]++
+		}
+	}
+
+	bound := 0.0
+	for _, person := range people {
+		var weights []float64
+		for _, preference := range person.Preferences {
+			if preference.id < 0 || preference.Weight <= 0 {
+				continue
+			}
+			others := 1
+			if preference.isTag {
+				others = tagged[preference.id]
+				for _, tag := range person.tagIDs {
+					if tag == preference.id {
+						others--
+					}
+				}
+			}
+			for i := 0; i < others && i < largest-1; i++ {
+				weights = append(weights, preference.Weight)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(weights)))
+		for i := 0; i < len(weights) && i < largest-1; i++ {
+			bound += weights[i]
+		}
+	}
+	return bound
+}
+
+// largestCapacity returns the most people any of the tables seats
+func largestCapacity(tables []table) (largest int) {
+	for _, table := range tables {
+		if table.capacity > largest {
+			largest = table.capacity
+		}
+	}
+	return largest
+}
+
+// printBoundFraction prints how much of the upper bound on the weight of preferences that can be satisfied (see
+// preferenceBound) the solution satisfies
+func printBoundFraction(w io.Writer, solution []table) {
+	people := make([]Person, 0, getNoOfPeople(solution))
+	for _, table := range solution {
+		people = append(people, seatedAt(table)...)
+	}
+	satisfied, bound := sumFunction(solution, scoring{}), preferenceBound(people, solution)
+	fraction := 100.0
+	if bound > 0 {
+		fraction = satisfied / bound * 100
+	}
+	fmt.Fprintf(w, "Satisfied %g of the most that could be (%g), which is %.1f%%", satisfied, bound, fraction)
+	fmt.Fprintln(w)
+}
+
+// printReciprocity prints how many preferences for people are mutual (where the two people prefer each other) and how
+// many are one-sided, followed by the most preferred people. Preferences for tags are left out, as they're for nobody
+// in particular. The people must have been indexed
+func printReciprocity(w io.Writer, people []Person) {
+	mutual := getMutual(people)
+	mutualPairs, oneSided, tags := 0, 0, 0
+	preferredBy := make([]int, len(people))
+	for _, person := range people {
+		mutualPairs += len(mutual[person.id])
+		seen := make(map[int]bool)
+		for _, preference := range person.Preferences {
+			if preference.isTag {
+				tags++
+				continue
+			}
+			if preference.id < 0 || seen[preference.id] {
+				continue
+			}
+			seen[preference.id] = true
+			preferredBy[preference.id]++
+		}
+		oneSided += len(seen) - len(mutual[person.id])
+	}
+	fmt.Fprintf(w, "%d mutual pairs (who prefer each other) and %d one-sided preferences for people, with %d preferences for tags", mutualPairs/2, oneSided, tags)
+	fmt.Fprintln(w)
+
+	ids := make([]int, len(people))
+	for i := range ids {
+		ids[i] = i
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return preferredBy[ids[i]] > preferredBy[ids[j]]
+	})
+	var most []string
+	for _, id := range ids {
+		if len(most) == analysisNamesShown || preferredBy[id] == 0 {
+			break
+		}
+		most = append(most, fmt.Sprintf("%s (%d)", people[id].Name, preferredBy[id]))
+	}
+	if len(most) > 0 {
+		fmt.Fprintf(w, "Most preferred: %s", strings.Join(most, ", "))
+		fmt.Fprintln(w)
+	}
+}
+
+// printAnalysis prints how many preferences are mutual, the groups of people linked to each other by preferences, an
+// upper bound on how much of their preferences can be satisfied, and whether each group could be sat at a table of its
+// own - if so, each group can be solved on its own
+func printAnalysis(w io.Writer, p Problem) {
+	printReciprocity(w, indexPeople(p.People))
+	components := preferenceComponents(indexPeople(p.People))
+	alone := 0
+	for _, component := range components {
+		if len(component) == 1 {
+			alone++
+		}
+	}
+	fmt.Fprintf(w, "%d groups of people linked by preferences, and %d people linked to nobody", len(components)-alone, alone)
+	fmt.Fprintln(w)
+	for i, component := range components {
+		if len(component) == 1 {
+			break
+		}
+		var names []string
+		for _, person := range component {
+			if len(names) == analysisNamesShown {
+				names = append(names, "...")
+				break
+			}
+			names = append(names, person.Name)
+		}
+		fmt.Fprintf(w, "- group %d: %d people (%s)", i+1, len(component), strings.Join(names, ", "))
+		fmt.Fprintln(w)
+	}
+
+	tables, err := newTables(p)
+	if err != nil {
+		return
+	}
+	people := indexPeople(p.People)
+	everyone := table{people: people, seated: make([]bool, len(people)), tagged: make([]int, len(tagIDs(people)))}
+	for _, person := range people {
+		everyone.seat(person)
+	}
+	fmt.Fprintf(w, "At most %g of the %g weight of preferences can be satisfied, as nobody can be sat with more than %d others", preferenceBound(people, tables), getTotalWeight([]table{everyone}), largestCapacity(tables)-1)
+	fmt.Fprintln(w)
+	if len(components) == alone {
+		return
+	}
+	fitted := fitComponents(components, tables)
+	if fitted == nil {
+		fmt.Fprintln(w, "The groups can't each be sat at a table of their own")
+		return
+	}
+	fmt.Fprintln(w, "Each group can be sat at a table of its own, so could be solved separately, e.g.:")
+	for i, tableNo := range fitted {
+		if tableNo == -1 {
+			break
+		}
+		fmt.Fprintf(w, "- group %d at %s (capacity %d)", i+1, tables[tableNo].name, tables[tableNo].capacity)
+		fmt.Fprintln(w)
+	}
+}
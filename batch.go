@@ -0,0 +1,92 @@
+package allocations
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// solutionSuffix is added in place of .json to the name of each problem file in a batch, for the file its solution is
+// written to
+const solutionSuffix = ".solution.json"
+
+// batchResult is the outcome of solving one of the problem files in a batch
+type batchResult struct {
+	file     string
+	solution Solution
+	err      error // why the file couldn't be solved, if it couldn't
+}
+
+// solveBatch solves each problem file (*.json) in the directory with the given configuration, writing each solution
+// next to its file, and returns how each went in order of filename. As many files as there are processors are solved at
+// once, each with its own timeout (if it has one). A file that can't be read or solved doesn't stop the others
+func solveBatch(ctx context.Context, dir string, cfg Config, timeout time.Duration) ([]batchResult, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	problems := files[:0]
+	for _, file := range files {
+		if !strings.HasSuffix(file, solutionSuffix) {
+			problems = append(problems, file)
+		}
+	}
+	sort.Strings(problems)
+
+	// the callbacks are for a single run, rather than many at once
+	cfg.Progress = nil
+	cfg.Checkpoint = nil
+
+	results := make([]batchResult, len(problems))
+	slots := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, file := range problems {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			solution, err := solveFile(ctx, file, cfg, timeout)
+			results[i] = batchResult{file: file, solution: solution, err: err}
+		}(i, file)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// solveFile solves the problem file, writing the solution to the file next to it. Running out of time still writes the
+// best solution found so far, as when solving a single file
+func solveFile(ctx context.Context, file string, cfg Config, timeout time.Duration) (Solution, error) {
+	problemFile, err := os.Open(file)
+	if err != nil {
+		return Solution{}, err
+	}
+	problem, err := LoadProblem(problemFile)
+	problemFile.Close()
+	if err != nil {
+		return Solution{}, err
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	solution, err := Solve(ctx, problem, cfg)
+	if err != nil && err != context.DeadlineExceeded {
+		return Solution{}, err
+	}
+
+	solutionRaw, err := json.Marshal(solution)
+	if err != nil {
+		return Solution{}, err
+	}
+	return solution, ioutil.WriteFile(strings.TrimSuffix(file, ".json")+solutionSuffix, append(solutionRaw, '\n'), 0644)
+}
@@ -0,0 +1,130 @@
+package allocations
+
+import (
+	"math/rand"
+	"runtime"
+)
+
+// candidate is a neighbour made by the candidate workers, as the swaps that make it from the solution
+type candidate struct {
+	swaps    []swap
+	cost     float64
+	affected []int // the tables whose parts of the cost the swaps change, when the cost is given from its parts
+}
+
+// candidateJob is what the candidate workers need to make and score neighbours of the solution at an iteration, which
+// they only read
+type candidateJob struct {
+	movable    []int
+	s          scoring
+	o          objective
+	swapCount  int
+	moveChance float64
+	parts      []costParts // each table's parts of the cost, when the cost is given from its parts
+	totalParts costParts
+	highest    float64
+}
+
+// candidateWorkers make an annealer's candidate neighbours at each iteration, in goroutines that last for the whole
+// run rather than being started at every iteration. There are no more goroutines than processors to run them, so each
+// makes every so many of the candidates in turn - each candidate has its own rng, so that the neighbours don't depend
+// on which goroutine makes them or how many there are
+type candidateWorkers struct {
+	rngs       []*rand.Rand
+	candidates []candidate
+	solutions  [][]table // each goroutine's own copy of the solution, which its neighbours are made in and then undone
+	start      []chan *candidateJob
+	done       chan struct{}
+}
+
+// newCandidateWorkers starts the goroutines to make the given number of candidates at each iteration, which run until
+// stop is called
+func newCandidateWorkers(candidates int) *candidateWorkers {
+	goroutines := candidates
+	if processors := runtime.GOMAXPROCS(0); goroutines > processors {
+		goroutines = processors
+	}
+	c := &candidateWorkers{
+		rngs:       make([]*rand.Rand, candidates),
+		candidates: make([]candidate, candidates),
+		solutions:  make([][]table, goroutines),
+		start:      make([]chan *candidateJob, goroutines),
+		done:       make(chan struct{}, goroutines),
+	}
+	for w := range c.rngs {
+		c.rngs[w] = rand.New(rand.NewSource(0))
+	}
+	for g := range c.start {
+		c.start[g] = make(chan *candidateJob)
+		go c.work(g)
+	}
+	return c
+}
+
+// work makes the goroutine's candidates each time it's given a job, until it's stopped
+func (c *candidateWorkers) work(g int) {
+	for job := range c.start[g] {
+		for w := g; w < len(c.candidates); w += len(c.start) {
+			c.candidates[w] = job.make(c.rngs[w], c.solutions[g], c.candidates[w])
+		}
+		c.done <- struct{}{}
+	}
+}
+
+// reset starts each goroutine's copy of the solution again from the given one, and reseeds each candidate's rng from
+// the annealer's, as the solution may have been exchanged with another annealer's since the last step
+func (c *candidateWorkers) reset(rng *rand.Rand, solution []table) {
+	for w := range c.rngs {
+		c.rngs[w].Seed(rng.Int63())
+	}
+	for g := range c.solutions {
+		c.solutions[g] = copyAssignment(solution)
+	}
+}
+
+// run makes every candidate for the job, returning once they're all made
+func (c *candidateWorkers) run(job *candidateJob) []candidate {
+	for g := range c.start {
+		c.start[g] <- job
+	}
+	for range c.start {
+		<-c.done
+	}
+	return c.candidates
+}
+
+// apply makes the swap on each goroutine's copy of the solution, to keep them in step with it
+func (c *candidateWorkers) apply(move swap) {
+	for g := range c.solutions {
+		applySwap(c.solutions[g], move)
+	}
+}
+
+// stop ends the goroutines
+func (c *candidateWorkers) stop() {
+	for g := range c.start {
+		close(c.start[g])
+	}
+}
+
+// make makes and scores a neighbour of the solution, then undoes it so that the solution is as it was, reusing the
+// space of the last candidate
+func (job *candidateJob) make(rng *rand.Rand, solution []table, last candidate) candidate {
+	swaps := getNeighbour(rng, solution, job.movable, job.swapCount, job.moveChance, last.swaps[:0])
+	affected := last.affected[:0]
+	var cost float64
+	if partsCost, fromParts := job.o.(partsObjective); fromParts {
+		affected = affectedTables(solution, swaps, affected)
+		candidateTotal := job.totalParts
+		for _, tableNo := range affected {
+			candidateTotal = candidateTotal.sub(job.parts[tableNo]).add(partsCost.tableParts(solution, tableNo, job.s))
+		}
+		cost = partsCost.fromParts(candidateTotal, job.highest)
+	} else {
+		cost = job.o.cost(solution, job.s)
+	}
+	for j := len(swaps) - 1; j >= 0; j-- {
+		undoSwap(solution, swaps[j])
+	}
+	return candidate{swaps: swaps, cost: cost, affected: affected}
+}
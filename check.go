@@ -0,0 +1,66 @@
+package allocations
+
+import (
+	"fmt"
+	"io"
+)
+
+// checkProblem returns everything wrong with the problem that would stop it being solved (or mean some of it is
+// ignored) under the given configuration, without annealing it
+func checkProblem(p Problem, cfg Config) (failures []string) {
+	seen := make(map[string]bool)
+	for _, person := range p.People {
+		if seen[person.Name] {
+			failures = append(failures, fmt.Sprintf("'%s' is in the input file more than once", person.Name))
+		}
+		seen[person.Name] = true
+	}
+	failures = append(failures, unknownNames(p)...)
+
+	tables, err := newTables(p)
+	if err != nil {
+		return append(failures, err.Error())
+	}
+	_, err = newScoring(p, tables, cfg, nil)
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+	p.People = indexPeople(p.People)
+	_, err = pinPeople(p, tables)
+	if err != nil {
+		return append(failures, err.Error())
+	}
+	if p.Assignment != nil {
+		_, err = seatNames(p.Assignment, p.People, tables)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("assignment to start from: %s", err))
+		}
+	}
+	return failures
+}
+
+// printCheck prints a summary of the size of the problem and the constraints on it
+func printCheck(w io.Writer, p Problem) {
+	seats, preferences, avoids := 0, 0, 0
+	for _, spec := range p.Tables {
+		seats += spec.Max
+	}
+	for _, person := range p.People {
+		preferences += len(person.Preferences)
+		avoids += len(person.Avoid)
+	}
+	fmt.Fprintf(w, "%d people, %d tables with %d seats and %d preferences", len(p.People), len(p.Tables), seats, preferences)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- avoids: %d", avoids)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- plus-ones: %d", len(p.PlusOnes))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- pairs to be kept apart: %d", len(p.Apart))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- groups: %d", len(p.Groups))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people pinned to a table: %d", len(p.Pinned))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people forbidden from some tables: %d", len(p.Forbid))
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,1081 @@
+package allocations
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version information, which can be set at build time with -ldflags "-X
+// github.com/mhbardsley/table-allocations.version=v1.2.3 -X github.com/mhbardsley/table-allocations.commit=abc1234"
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
+// printBreakdown prints the cost of an assignment under the chosen cost function, along with what makes it up
+func printBreakdown(w io.Writer, assignment []table, s scoring, mode string, costFunction func([]table, scoring) float64) {
+	sameTable, adjacentTable, splitPlusOnes, notApart, splitGroups, forbidden := 0, 0, 0, 0, 0, 0
+	for tableNo, table := range assignment {
+		for _, person := range table.people {
+			if person.empty {
+				continue
+			}
+			plusOne, exists := s.plusOnes[person.id]
+			if exists && !table.has(plusOne) {
+				splitPlusOnes++
+			}
+			for _, id := range s.apart[person.id] {
+				if person.id < id && table.has(id) {
+					notApart++
+				}
+			}
+			for _, id := range s.together[person.id] {
+				if person.id < id && !table.has(id) {
+					splitGroups++
+				}
+			}
+			if isForbidden(s.forbidden, person.id, tableNo) {
+				forbidden++
+			}
+			for _, preference := range person.Preferences {
+				if preference.Weight <= 0 {
+					continue
+				}
+				if table.matches(preference, person) > 0 {
+					sameTable++
+				} else if adjacentMatches(assignment, tableNo, preference, person) > 0 {
+					adjacentTable++
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "Cost under the %s cost function: %g", mode, costFunction(assignment, s))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- weight of preferences sat at the same table: %g of %g", sumFunction(assignment, scoring{}), getTotalWeight(assignment))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- preferences sat at the same table: %d of %d", sameTable, getTotalPrefs(assignment))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- preferences sat at an adjacent table: %d (credit %g each)", adjacentTable, s.adjacentCredit)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people given a preference: %d of %d", int(countFunction(assignment, scoring{})), getNoOfPeople(assignment))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- plus-ones not sat together: %d", splitPlusOnes)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- pairs to be kept apart sat together: %d", notApart)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- pairs in a group not sat together: %d", splitGroups)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people sat at a table they're forbidden from: %d", forbidden)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people sat with fewer than %d of their preferences: %d", s.minSatisfied, getBelowMinimum(assignment, s.minSatisfied))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people sat with someone they want to avoid: %d (penalty %g each)", getAvoided(assignment), s.avoidPenalty)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people sat with none of their preferences: %d (penalty %g each)", getLonely(assignment), s.lonelyPenalty)
+	fmt.Fprintln(w)
+	worst, worstOff := getWorstOff(assignment)
+	fmt.Fprintf(w, "- preferences satisfied for the worst-off person: %d (for %d people)", worst, worstOff)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- mutual pairs sat together: %d (bonus %g each)", getMutualTogether(assignment, s), s.mutualBonus)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people sat at a different table from before: %d (penalty %g each)", getMoved(assignment, s), s.stability)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- VIP scores times the desirability of their tables: %g (weight %g)", getDesirability(assignment), s.desirability)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- people away from the balanced mix of the attribute: %g (penalty %g each)", getImbalance(assignment, s), s.balance)
+	fmt.Fprintln(w)
+}
+
+// newJSONEncoder returns an encoder writing compact JSON, or JSON indented by two spaces if pretty
+func newJSONEncoder(w io.Writer, pretty bool) *json.Encoder {
+	encoder := json.NewEncoder(w)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder
+}
+
+// printStats writes the run statistics in the given format, either text or json (indented if pretty)
+func printStats(w io.Writer, runStats Stats, format string, pretty bool) error {
+	if format == "json" {
+		return newJSONEncoder(w, pretty).Encode(runStats)
+	}
+
+	fmt.Fprintf(w, "Cost went from %g to %g (out of a possible %g)", runStats.InitialCost, runStats.FinalCost, runStats.MaxPossibleCost)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%d temperature steps, %d cost evaluations, %.1f%% of neighbours accepted, %.2fs elapsed", runStats.Steps, runStats.Evaluations, runStats.AcceptanceRatio*100, runStats.ElapsedSeconds)
+	fmt.Fprintln(w)
+	if runStats.Reheats > 0 {
+		fmt.Fprintf(w, "The temperature was raised %d times after the best cost stalled", runStats.Reheats)
+		fmt.Fprintln(w)
+	}
+	fmt.Fprint(w, "Solutions passed down by each annealer, from the second coldest to the hottest:")
+	for i := 1; i < len(runStats.Exchanges); i++ {
+		fmt.Fprintf(w, " %d", runStats.Exchanges[i])
+	}
+	fmt.Fprintln(w)
+	if hottest := len(runStats.Exchanges) - 1; hottest > 0 && runStats.Exchanges[hottest] == 0 {
+		fmt.Fprintln(w, "The hottest annealer never passed down a better solution - consider lowering its temperature with -b or -a")
+	}
+	return nil
+}
+
+// restartSummary is how the final costs were spread across the restarts, to show how reliable the parameters are
+type restartSummary struct {
+	Runs     int     `json:"runs"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Mean     float64 `json:"mean"`
+	StdDev   float64 `json:"stdDev"` // the standard deviation over the runs
+	BestSeed int64   `json:"bestSeed"`
+}
+
+// summariseRestarts summarises the final cost of each restart, where the first was run with the given seed
+func summariseRestarts(costs []float64, seed int64) restartSummary {
+	summary := restartSummary{Runs: len(costs), Min: math.Inf(1), Max: math.Inf(-1)}
+	for i, cost := range costs {
+		if cost > summary.Max {
+			summary.Max = cost
+			summary.BestSeed = seed + int64(i)
+		}
+		summary.Min = math.Min(summary.Min, cost)
+		summary.Mean += cost / float64(len(costs))
+	}
+	for _, cost := range costs {
+		summary.StdDev += (cost - summary.Mean) * (cost - summary.Mean) / float64(len(costs))
+	}
+	summary.StdDev = math.Sqrt(summary.StdDev)
+	return summary
+}
+
+// printSummary writes the restart summary in the given format, either text or json (indented if pretty)
+func printSummary(w io.Writer, summary restartSummary, format string, pretty bool) error {
+	if format == "json" {
+		return newJSONEncoder(w, pretty).Encode(summary)
+	}
+
+	fmt.Fprintf(w, "Final costs over %d runs: min %g, max %g, mean %.2f, standard deviation %.2f", summary.Runs, summary.Min, summary.Max, summary.Mean, summary.StdDev)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "The best was found with seed %d", summary.BestSeed)
+	fmt.Fprintln(w)
+	return nil
+}
+
+// printSolution prints the people sat at each table, with how many were given a preference and the solution's cost
+func printSolution(w io.Writer, solution []table, cost float64, s scoring) {
+	// only preferences at the same table are reported, so no credit is given for adjacent tables (preferences are
+	// counted by their weight)
+	reported := scoring{plusOnes: s.plusOnes}
+	fmt.Fprintf(w, "Found a solution where %d people are given a preference (i.e. %d people have not been allocated at least one of their preferences). %g preferences are given in total", int(countFunction(solution, reported)), getNoOfPeople(solution)-int(countFunction(solution, reported)), sumFunction(solution, reported))
+	fmt.Fprintln(w)
+	if belowMinimum := getBelowMinimum(solution, s.minSatisfied); belowMinimum > 0 {
+		fmt.Fprintf(w, "Could not find a solution giving everyone at least %d of their preferences: %d people have fewer", s.minSatisfied, belowMinimum)
+		fmt.Fprintln(w)
+	}
+	if avoided := getAvoided(solution); avoided > 0 {
+		fmt.Fprintf(w, "Could not keep everyone away from the people they want to avoid: %d are sat together", avoided)
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+	for _, table := range solution {
+		// a table that must be filled only has its capacity shown
+		if table.minimum > 0 && table.minimum < table.capacity {
+			fmt.Fprintf(w, "%s (capacity %d, at least %d)", table.name, table.capacity, table.minimum)
+		} else {
+			fmt.Fprintf(w, "%s (capacity %d)", table.name, table.capacity)
+		}
+		fmt.Fprintln(w)
+		seated := inInputOrder(table)
+		for _, person := range seated {
+			fmt.Fprintf(w, "- %s", person.Name)
+			fmt.Fprintln(w)
+		}
+		for i := len(seated); i < table.capacity; i++ {
+			fmt.Fprintln(w, "- (empty)")
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Final cost %g, with %.1f%% of preferences (by weight) satisfied", cost, getSatisfaction(solution))
+	fmt.Fprintln(w)
+}
+
+// printProgress prints how a run is going to stderr, so that it doesn't get mixed up with the solution
+func printProgress(p Progress) {
+	fmt.Fprintf(os.Stderr, "Step %d: temperature %g, best cost %g (from %g), %.1fs elapsed", p.Step, p.Temperature, p.BestCost, p.InitialCost, p.Elapsed.Seconds())
+	fmt.Fprintln(os.Stderr)
+}
+
+// printVerbose prints the temperature and the coldest annealer's cost after each temperature step to stderr
+func printVerbose(p Progress) {
+	fmt.Fprintf(os.Stderr, "Step %d: temperature %g, coldest annealer's cost %g", p.Step, p.Temperature, p.ColdestCost)
+	fmt.Fprintln(os.Stderr)
+}
+
+// loadInput reads the problem in the given format, where a CSV only holds the people so the tables are given separately.
+// If strict, any field in the JSON that isn't known is an error
+func loadInput(r io.Reader, format string, tables string, strict bool) (Problem, error) {
+	if format == "json" {
+		return loadProblem(r, strict)
+	}
+
+	// the tables can instead be given with -tablesize, which is checked once the input is loaded
+	var p Problem
+	for _, capacity := range strings.Split(tables, ",") {
+		if tables == "" {
+			break
+		}
+		max, err := strconv.Atoi(strings.TrimSpace(capacity))
+		if err != nil {
+			return Problem{}, fmt.Errorf("table capacity not understood: %w", err)
+		}
+		p.Tables = append(p.Tables, TableSpec{Max: max})
+	}
+	people, err := LoadPeopleCSV(r)
+	if err != nil {
+		return Problem{}, err
+	}
+	p.People = people
+	return p, nil
+}
+
+// printReport prints how many of their preferences each person was sat with, and who they want to avoid they were sat
+// with, from the most unhappy to the least - followed by the people who gave no preferences, who can't be unhappy with
+// who they're sat with
+func printReport(w io.Writer, solution []table) {
+	type personReport struct {
+		name               string
+		satisfied, total   int
+		missing, avoidedBy []string
+	}
+	var reports []personReport
+	for _, table := range solution {
+		for _, person := range table.people {
+			if person.empty {
+				continue
+			}
+			// anyone they'd rather not be sat with (a preference with a negative weight) is flagged like an avoid
+			report := personReport{name: person.Name, total: wanted(person)}
+			for _, preference := range person.Preferences {
+				matched := table.matches(preference, person) > 0
+				switch {
+				case preference.Weight <= 0 && matched:
+					report.avoidedBy = append(report.avoidedBy, preference.Name)
+				case preference.Weight <= 0:
+				case matched:
+					report.satisfied++
+				default:
+					report.missing = append(report.missing, preference.Name)
+				}
+			}
+			for i, id := range person.avoidIDs {
+				if table.has(id) {
+					report.avoidedBy = append(report.avoidedBy, person.Avoid[i])
+				}
+			}
+			reports = append(reports, report)
+		}
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if (reports[i].total == 0) != (reports[j].total == 0) {
+			return reports[j].total == 0
+		}
+		if reports[i].satisfied != reports[j].satisfied {
+			return reports[i].satisfied < reports[j].satisfied
+		}
+		return len(reports[i].missing) > len(reports[j].missing)
+	})
+
+	for _, report := range reports {
+		if report.total == 0 {
+			fmt.Fprintf(w, "%s: no preferences given", report.name)
+		} else {
+			fmt.Fprintf(w, "%s: %d/%d satisfied", report.name, report.satisfied, report.total)
+		}
+		if len(report.missing) > 0 {
+			fmt.Fprintf(w, " (missing: %s)", strings.Join(report.missing, ", "))
+		}
+		if len(report.avoidedBy) > 0 {
+			fmt.Fprintf(w, " - sat with %s, who they want to avoid", strings.Join(report.avoidedBy, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w)
+	printWishes(w, solution)
+}
+
+// the most people printWishes lists for each table
+const wishesPerTable = 5
+
+// printWishes prints, for each table, the people sat elsewhere who would satisfy the most preferences if they were
+// moved to it - both their own preferences for people at the table and the table's preferences for them
+func printWishes(w io.Writer, solution []table) {
+	type wish struct {
+		name, from      string
+		wants, wantedBy []string
+	}
+	fmt.Fprintln(w, "People who would satisfy more preferences at another table:")
+	for tableNo, table := range solution {
+		var wishes []wish
+		for otherNo, other := range solution {
+			if otherNo == tableNo {
+				continue
+			}
+			for _, person := range other.people {
+				if person.empty {
+					continue
+				}
+				candidate := wish{name: person.Name, from: other.name}
+				for _, preference := range person.Preferences {
+					if preference.Weight > 0 && table.matches(preference, person) > 0 {
+						candidate.wants = append(candidate.wants, preference.Name)
+					}
+				}
+				for _, sat := range table.people {
+					if !sat.empty && prefersPerson(sat, person) {
+						candidate.wantedBy = append(candidate.wantedBy, sat.Name)
+					}
+				}
+				if len(candidate.wants)+len(candidate.wantedBy) > 0 {
+					wishes = append(wishes, candidate)
+				}
+			}
+		}
+		if len(wishes) == 0 {
+			continue
+		}
+		sort.SliceStable(wishes, func(i, j int) bool {
+			return len(wishes[i].wants)+len(wishes[i].wantedBy) > len(wishes[j].wants)+len(wishes[j].wantedBy)
+		})
+		if len(wishes) > wishesPerTable {
+			wishes = wishes[:wishesPerTable]
+		}
+
+		fmt.Fprintf(w, "%s:", table.name)
+		fmt.Fprintln(w)
+		for _, wish := range wishes {
+			var edges []string
+			if len(wish.wants) > 0 {
+				edges = append(edges, "wants "+strings.Join(wish.wants, ", "))
+			}
+			if len(wish.wantedBy) > 0 {
+				edges = append(edges, "wanted by "+strings.Join(wish.wantedBy, ", "))
+			}
+			fmt.Fprintf(w, "- %s, from %s: %d more (%s)", wish.name, wish.from, len(wish.wants)+len(wish.wantedBy), strings.Join(edges, "; "))
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// prefersPerson reports whether the person prefers the other (with a positive weight), by name or by one of their tags
+func prefersPerson(person Person, other Person) bool {
+	for _, preference := range person.Preferences {
+		if preference.Weight <= 0 {
+			continue
+		}
+		if !preference.isTag && preference.id == other.id {
+			return true
+		}
+		if preference.isTag {
+			for _, tag := range other.tagIDs {
+				if tag == preference.id {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// writeCheckpoint saves the checkpoint to the named file, replacing it all at once so that a crash part of the way
+// through writing never leaves a broken checkpoint behind
+func writeCheckpoint(filename string, c Checkpoint) error {
+	checkpointRaw, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filename+".tmp", checkpointRaw, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// printVersion prints the version and build commit, falling back to the module version when installed with go install
+func printVersion() {
+	if info, ok := debug.ReadBuildInfo(); ok && version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	fmt.Printf("table-allocations %s (commit %s)", version, commit)
+	fmt.Println()
+}
+
+// weightFlags are the -w term=value flags, which can be given more than once
+type weightFlags []string
+
+func (w *weightFlags) String() string {
+	return strings.Join(*w, ",")
+}
+
+func (w *weightFlags) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("weight must be given as term=value, e.g. avoid=10, but is %s", value)
+	}
+	*w = append(*w, value)
+	return nil
+}
+
+// Run runs the table-allocations command line with the given arguments (not including the program name)
+func Run(args []string) {
+	// the score subcommand scores an existing assignment rather than annealing a new one
+	scoreOnly := len(args) > 0 && args[0] == "score"
+	if scoreOnly {
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet("table-allocations", flag.ExitOnError)
+	costFunctionPtr := flags.String("m", "hybrid", "Whether the program should: maximise the total number of satisifed preferences; maximise the number of people with at least 1 satisfied preference; provide a hybrid of these; or maximise the number of preferences satisfied for the worst-off person (maximin)")
+	filePtr := flags.String("f", "input.json", "The filename to be checked, or - (or an empty name) to read from stdin")
+	solverPtr := flags.String("solver", "anneal", "How the problem is solved: anneal; greedy, sitting people (those with the most preferences first) at the table that most improves the cost without annealing, to compare annealing against; or optimal, trying every way of seating people to find the best (for at most 12 people, besides anyone pinned)")
+	optimalPtr := flags.Bool("optimal", false, "Shorthand for -solver optimal")
+	initPtr := flags.String("init", "random", "How the starting solution is chosen: random; greedy, sitting people (those with the most preferences first) at the table that most improves the cost; or clustered, sitting groups of people who prefer each other together")
+	baseTemperaturePtr := flags.String("b", "1.0", "The lowest base temperature for the concurrent annealers (temperature increases by 2^i for each goroutine i) - lower is quicker; higher is more optimal")
+	autoTempPtr := flags.Bool("autotemp", false, "Estimate the base temperature (in place of -b) from a short random walk, so that about 80% of moves are accepted at first")
+	endTemperaturePtr := flags.String("e", "0.00001", "The lowest final temperature for the concurrent annealers (temperature increases by 2^i for each goroutine i) - lower is more optimal; higher is quicker")
+	coolingRatePtr := flags.String("c", "0.9", "The rate of cooling for each step in the annealing process (a number greater than 0 and less than 1) - closer to 0 is quicker; closer to 1 is more optimal")
+	adaptiveCoolingPtr := flags.Bool("adaptiveCooling", false, "Cool more slowly while the fraction of moves accepted falls to below half of what it was in a step (and back towards -c once it falls by less than a tenth), when cooling geometrically")
+	coolingSchedulePtr := flags.String("cooling", "geometric", "How the temperature is lowered at each step: geometric, multiplying it by the cooling rate; or linear, lowering it by the same amount over the number of cooling steps")
+	coolingStepsPtr := flags.String("coolingSteps", "100", "The number of steps taken to cool to the final temperature, when cooling linearly - lower is quicker; higher is more optimal")
+	iterationPtr := flags.String("i", "1000", "The number of iterations at each step of the annealing process, or auto to scale it with the number of people (see -iPerPerson) - lower is quicker; higher is more optimal")
+	iterationsPerPersonPtr := flags.String("iPerPerson", "20", "The number of iterations at each step for each person, with -i auto")
+	swapPtr := flags.String("s", "1", "The number of swaps in each iteration of the anneling process - lower is quicker; higher is more optimal")
+	swapSchedulePtr := flags.String("swapSchedule", "fixed", "How the number of swaps changes as the annealers cool: fixed; or decreasing, falling from the number of swaps at the base temperature to 1 at the final temperature")
+	neighbourMixPtr := flags.String("neighbourMix", "0", "The chance (between 0 and 1) that each swap moves someone into an empty seat at another table, rather than swapping any two seats")
+	formatInPtr := flags.String("format-in", "json", "The format of the input file, either json or csv (with name and preferences columns, where preferences are separated by semicolons)")
+	matrixPtr := flags.String("matrix", "", "A CSV of how much each person wants to be sat with each other person (negative to keep them apart), added to their preferences, where the header row names the people and each row after it is for the person named in the same place in the header")
+	tablesPtr := flags.String("tables", "", "The capacities of the tables when reading a CSV, separated by commas, e.g. 8,8,10")
+	tableSizePtr := flags.String("tablesize", "", "The capacity of the tables to make (as many as are needed to seat everyone) when the input file doesn't give any tables, e.g. 8")
+	outputPtr := flags.String("o", "", "The file to write the solution to, which is created or truncated (stdout if not given)")
+	formatPtr := flags.String("format", "text", "The format to print the solution in: text; json; dot, a graph of who is sat with whom (e.g. for dot -Tpng); or html, a printable page with a card for each table")
+	prettyPtr := flags.Bool("pretty", false, "Indent JSON output (the solution with -format json, and -stats or -summary json) by two spaces, rather than keeping it compact for piping")
+	statsPtr := flags.String("stats", "", "Print statistics about the run, such as how many better solutions each annealer passed down to a colder one, as either text or json")
+	checkPtr := flags.Bool("check", false, "Check that the input file can be solved (with no duplicate or unknown names, and constraints that can be met), print a summary of it and exit without annealing")
+	analyzePtr := flags.Bool("analyze", false, "Print the groups of people linked to each other by preferences to stderr before solving, and whether each group could be sat at a table of its own")
+	summaryPtr := flags.String("summary", "", "Print the minimum, maximum, mean and standard deviation of the final costs over the restarts, and the seed of the best, as either text or json (to stderr)")
+	statsFilePtr := flags.String("statsFile", "", "The file to write statistics to when -stats is given (stderr if not given)")
+	tracePtr := flags.String("trace", "", "The CSV file to record the temperature, best cost and accepted and rejected moves of each temperature step to, e.g. trace.csv")
+	strictPtr := flags.Bool("strict", false, "Treat preferences and avoids naming someone who isn't in the input file (or the person themselves), and any field in it that isn't known (e.g. a misspelling), as an error rather than a warning")
+	reportPtr := flags.Bool("report", false, "After the solution, print each person with how many of their preferences they were sat with, from the most unhappy to the least (to stderr with -format json)")
+	quietPtr := flags.Bool("quiet", false, "Print nothing but the solution (and any errors) - no seed, warnings, progress, report or other diagnostics")
+	verbosePtr := flags.Bool("verbose", false, "Print the temperature and the coldest annealer's cost to stderr after each temperature step, and how long annealing took at the end")
+	flags.BoolVar(verbosePtr, "v", false, "Shorthand for -verbose")
+	progressPtr := flags.Bool("progress", false, "Print the temperature, best cost and elapsed time to stderr after each temperature step")
+	cpuProfilePtr := flags.String("cpuprofile", "", "The file to write a CPU profile of solving to (for go tool pprof), to find where the time goes")
+	memProfilePtr := flags.String("memprofile", "", "The file to write a heap profile to once solving has finished (for go tool pprof), to find what is allocating memory")
+	versionPtr := flags.Bool("version", false, "Print the version and build commit, then exit")
+	concurrentAnnealerPtr := flags.String("a", "6", "The number of concurrent annealing goroutines")
+	candidateWorkersPtr := flags.String("candidates", "1", "The number of neighbours each annealer makes at once (in up to one goroutine for each processor) at each iteration, only considering the best - higher can be more optimal for the time taken on large inputs")
+	exchangePtr := flags.String("exchange", "deterministic", "How the concurrent annealers swap solutions after each step: deterministic, passing a hotter annealer's solution down only when it is better; or metropolis, sometimes passing down a worse one")
+	stallLimitPtr := flags.String("stallLimit", "0", "Stop early if the best cost hasn't improved for this many temperature steps (0 never stops early) - lower is quicker; higher is more optimal")
+	reheatAfterStallPtr := flags.String("reheatAfterStall", "0", "Raise the temperature again if the best cost hasn't improved for this many temperature steps (0 never reheats)")
+	reheatFactorPtr := flags.String("reheatFactor", "10", "The factor the temperature is raised by when reheating (a number greater than 1), up to the base temperature")
+	maxReheatsPtr := flags.String("maxReheats", "5", "The most times the temperature is raised again, so that runs still finish")
+	polishPtr := flags.Bool("polish", false, "After cooling, keep making any swap that improves the best solution until none do")
+	minSatisfiedPtr := flags.String("minSatisfiedPerPerson", "0", "The number of their preferences everyone must be sat with - solutions where someone has fewer are heavily penalised")
+	penaltyPtr := flags.String("penalty", "0", "The cost taken off for each hard constraint broken (a plus-one not sat together, a pair not kept apart or together in a group, or someone below -minSatisfiedPerPerson), where 0 picks one high enough that no preferences make up for it")
+	lonelyPenaltyPtr := flags.String("lonelyPenalty", "0", "The cost taken off for each person sat with none of their preferences (out of those who have any), to spread satisfied preferences more fairly")
+	avoidPenaltyPtr := flags.String("avoidPenalty", "10", "The cost taken off for each person sat with someone they want to avoid (see avoid in the input file)")
+	stabilityWeightPtr := flags.String("stabilityWeight", "0", "The cost taken off for each person sat at a different table from the previous assignment (previous in the input file), to keep people where they were")
+	desirabilityWeightPtr := flags.String("desirabilityWeight", "0", "The extra cost given for each person's VIP score (vip in the input file) times the desirability of the table they're sat at, to sit VIPs at the most desirable tables")
+	balancePtr := flags.String("balance", "", "The attribute (in each person's attributes in the input file, e.g. department) to balance the tables by, so that each table's mix of it is close to everyone's")
+	balanceWeightPtr := flags.String("balanceWeight", "1", "The cost taken off for each person a table is away from everyone's mix of the attribute, with -balance")
+	mutualBonusPtr := flags.String("mutualBonus", "0", "The extra cost given for each pair sat together who both prefer each other, on top of their two preferences")
+	checkpointPtr := flags.String("checkpoint", "", "The file to save the best solution so far and the temperature to every few steps, so that the run can be carried on with -resume")
+	resumePtr := flags.String("resume", "", "A checkpoint file (see -checkpoint) to carry on annealing from")
+	diffPtr := flags.String("diff", "", "A solution printed with -format json to compare with the one given after the flags, e.g. -diff old.json new.json, printing who moved")
+	batchPtr := flags.String("batch", "", "A directory of problem files (*.json) to solve in place of the input file, writing each solution next to its file as name.solution.json")
+	servePtr := flags.String("serve", "", "The address to serve POST /solve requests on, e.g. :8080, solving the problem in each request's body in place of the input file")
+	maxTimePtr := flags.String("maxtime", "", "Keep annealing for this long, e.g. 10s, starting again from the base temperature whenever it cools, and give the best solution found (for each restart and round)")
+	timeoutPtr := flags.String("timeout", "", "The longest to spend annealing, e.g. 30s, after which the best solution so far is given (no limit if not given)")
+	topKPtr := flags.String("topk", "1", "The number of distinct solutions to print, best first, to choose between")
+	topKDistinctPtr := flags.String("topkDistinct", "0.1", "The fraction of people who must be sat at a different table for solutions to count as distinct, with -topk")
+	roundsPtr := flags.String("rounds", "1", "The number of rounds to seat everyone for (e.g. courses where people move tables), printing each in turn")
+	metPenaltyPtr := flags.String("metPenalty", "2", "The cost taken off for each time a pair sat together have been sat together in an earlier round, when there's more than one")
+	restartsPtr := flags.String("restarts", "1", "The number of independent runs to take the best of, with seeds counting up from the given one - higher is more optimal; lower is quicker")
+	seedPtr := flags.String("seed", "", "The seed for the random number generator, so that a run can be repeated (if not given, the time is used and printed to stderr)")
+	adjacentCreditPtr := flags.String("adjacentTableCredit", "0.5", "The credit given for a preference sat at an adjacent table (see adjacentTables in the input file), where a preference at the same table is worth 1")
+	var weights weightFlags
+	flags.Var(&weights, "w", "The weight of a term of the cost, as term=value (e.g. -w avoid=20 -w lonely=1), which can be given more than once and wins over the flag for that weight - the terms are "+strings.Join(ObjectiveTerms, ", ")+", where preferences multiplies each preference's own weight")
+
+	flags.Parse(args)
+
+	// diagnostics go to stderr, unless asked to be quiet (which wins over asking for any of them)
+	diagnostics := io.Writer(os.Stderr)
+	if *quietPtr {
+		diagnostics = ioutil.Discard
+		*progressPtr = false
+		*verbosePtr = false
+		*reportPtr = false
+	}
+
+	if *versionPtr {
+		printVersion()
+		return
+	}
+
+	// comparing two solutions doesn't need the input file
+	if *diffPtr != "" {
+		before, err := loadSolutionFile(*diffPtr)
+		if err != nil {
+			log.Fatal("error reading old solution file: ", err)
+		}
+		after, err := loadSolutionFile(flags.Arg(0))
+		if err != nil {
+			log.Fatal("error reading new solution file: ", err)
+		}
+		printDiff(os.Stdout, before, after)
+		return
+	}
+
+	if *formatInPtr != "json" && *formatInPtr != "csv" {
+		log.Fatal("provided input format not understood")
+	}
+	if *formatPtr != "text" && *formatPtr != "json" && *formatPtr != "dot" && *formatPtr != "html" {
+		log.Fatal("provided output format not understood")
+	}
+	if *statsPtr != "" && *statsPtr != "text" && *statsPtr != "json" {
+		log.Fatal("provided stats format not understood")
+	}
+
+	// a resumed run carries on from the checkpoint's solution and temperature, with its seed unless given another
+	var resumed *Checkpoint
+	if *resumePtr != "" {
+		checkpointRaw, err := ioutil.ReadFile(*resumePtr)
+		if err != nil {
+			log.Fatal("error opening checkpoint: ", err)
+		}
+		resumed = &Checkpoint{}
+		err = json.Unmarshal(checkpointRaw, resumed)
+		if err != nil {
+			log.Fatal("error making sense of checkpoint: ", err)
+		}
+	}
+
+	// use the given seed, or generate one from the time and say what it was so the run can be repeated
+	seed := time.Now().Unix()
+	if *seedPtr != "" {
+		var err error
+		seed, err = strconv.ParseInt(*seedPtr, 10, 64)
+		if err != nil {
+			log.Fatal("provided seed not understood: ", err)
+		}
+	} else if resumed != nil {
+		seed = resumed.Seed
+	} else if *servePtr == "" && *batchPtr == "" && !*checkPtr {
+		fmt.Fprintf(diagnostics, "Using seed %d", seed)
+		fmt.Fprintln(diagnostics)
+	}
+
+	var cfg Config
+	cfg.Mode = *costFunctionPtr
+	switch *solverPtr {
+	case "anneal":
+		cfg.Solver = AnnealSolver
+	case "greedy":
+		cfg.Solver = GreedySolver
+	case "optimal":
+		cfg.Solver = OptimalSolver
+	default:
+		log.Fatal("provided solver not understood")
+	}
+	if *optimalPtr {
+		cfg.Solver = OptimalSolver
+	}
+	switch *initPtr {
+	case "random":
+		cfg.Initialisation = RandomInit
+	case "greedy":
+		cfg.Initialisation = GreedyInit
+	case "clustered":
+		cfg.Initialisation = ClusteredInit
+	default:
+		log.Fatal("provided initialisation not understood")
+	}
+	cfg.BaseTemperature, _ = strconv.ParseFloat(*baseTemperaturePtr, 64)
+	if *autoTempPtr {
+		cfg.TargetAcceptance = 0.8
+	}
+	cfg.FinalTemperature, _ = strconv.ParseFloat(*endTemperaturePtr, 64)
+	cfg.CoolingRate, _ = strconv.ParseFloat(*coolingRatePtr, 64)
+	cfg.CoolingSteps, _ = strconv.Atoi(*coolingStepsPtr)
+	switch *swapSchedulePtr {
+	case "fixed":
+		cfg.SwapSchedule = FixedSwaps
+	case "decreasing":
+		cfg.SwapSchedule = DecreasingSwaps
+	default:
+		log.Fatal("provided swap schedule not understood")
+	}
+	switch *coolingSchedulePtr {
+	case "geometric":
+		cfg.CoolingSchedule = Geometric
+	case "linear":
+		cfg.CoolingSchedule = Linear
+	default:
+		log.Fatal("provided cooling schedule not understood")
+	}
+	if *iterationPtr == "auto" {
+		cfg.IterationsPerPerson, _ = strconv.Atoi(*iterationsPerPersonPtr)
+	} else {
+		cfg.InternalIterations, _ = strconv.Atoi(*iterationPtr)
+	}
+	cfg.SwapCount, _ = strconv.Atoi(*swapPtr)
+	cfg.NeighbourMix, _ = strconv.ParseFloat(*neighbourMixPtr, 64)
+	cfg.ConcurrentAnnealers, _ = strconv.Atoi(*concurrentAnnealerPtr)
+	cfg.CandidateWorkers, _ = strconv.Atoi(*candidateWorkersPtr)
+	switch *exchangePtr {
+	case "deterministic":
+		cfg.Exchange = Deterministic
+	case "metropolis":
+		cfg.Exchange = Metropolis
+	default:
+		log.Fatal("provided replica exchange not understood")
+	}
+	cfg.StallLimit, _ = strconv.Atoi(*stallLimitPtr)
+	cfg.ReheatAfterStall, _ = strconv.Atoi(*reheatAfterStallPtr)
+	cfg.ReheatFactor, _ = strconv.ParseFloat(*reheatFactorPtr, 64)
+	cfg.MaxReheats, _ = strconv.Atoi(*maxReheatsPtr)
+	cfg.Polish = *polishPtr
+	cfg.AdaptiveCooling = *adaptiveCoolingPtr
+	if *maxTimePtr != "" {
+		var err error
+		cfg.MaxTime, err = time.ParseDuration(*maxTimePtr)
+		if err != nil {
+			log.Fatal("provided max time not understood: ", err)
+		}
+	}
+	cfg.AdjacentTableCredit, _ = strconv.ParseFloat(*adjacentCreditPtr, 64)
+	cfg.MinSatisfiedPerPerson, _ = strconv.Atoi(*minSatisfiedPtr)
+	cfg.AvoidPenalty, _ = strconv.ParseFloat(*avoidPenaltyPtr, 64)
+	cfg.LonelyPenalty, _ = strconv.ParseFloat(*lonelyPenaltyPtr, 64)
+	cfg.Penalty, _ = strconv.ParseFloat(*penaltyPtr, 64)
+	cfg.MutualBonus, _ = strconv.ParseFloat(*mutualBonusPtr, 64)
+	cfg.DesirabilityWeight, _ = strconv.ParseFloat(*desirabilityWeightPtr, 64)
+	cfg.StabilityWeight, _ = strconv.ParseFloat(*stabilityWeightPtr, 64)
+	cfg.Balance = *balancePtr
+	cfg.BalanceWeight, _ = strconv.ParseFloat(*balanceWeightPtr, 64)
+	cfg.Seed = seed
+	cfg.Restarts, _ = strconv.Atoi(*restartsPtr)
+	cfg.MetPenalty, _ = strconv.ParseFloat(*metPenaltyPtr, 64)
+	for _, weight := range weights {
+		parts := strings.SplitN(weight, "=", 2)
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			log.Fatalf("provided weight for %s not understood: %s", parts[0], err)
+		}
+		err = cfg.ObjectiveWeights.Set(parts[0], value)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	rounds, _ := strconv.Atoi(*roundsPtr)
+	cfg.TopK, _ = strconv.Atoi(*topKPtr)
+	cfg.TopKDistinct, _ = strconv.ParseFloat(*topKDistinctPtr, 64)
+	if cfg.TopK > 1 && rounds > 1 {
+		log.Fatal("-topk can't be used with more than one round")
+	}
+	runs := cfg.Restarts
+	if runs < 1 {
+		runs = 1
+	}
+	if *progressPtr {
+		cfg.Progress = printProgress
+	}
+	if *verbosePtr {
+		printProgress := cfg.Progress
+		cfg.Progress = func(p Progress) {
+			if printProgress != nil {
+				printProgress(p)
+			}
+			printVerbose(p)
+		}
+	}
+
+	if resumed != nil {
+		if resumed.Temperature <= cfg.FinalTemperature {
+			log.Fatal("the checkpointed run had already cooled to the final temperature")
+		}
+		cfg.BaseTemperature = resumed.Temperature
+		cfg.TargetAcceptance = 0
+	}
+	if *checkpointPtr != "" {
+		cfg.Checkpoint = func(c Checkpoint) {
+			err := writeCheckpoint(*checkpointPtr, c)
+			if err != nil {
+				fmt.Fprintln(diagnostics, "warning: error writing checkpoint:", err)
+			}
+		}
+	}
+
+	err := cfg.Validate()
+	if err != nil {
+		log.Fatal("invalid annealing parameters: ", err)
+	}
+
+	// serving takes the problem from each request, so there's no input file to read
+	if *servePtr != "" {
+		var timeout time.Duration
+		if *timeoutPtr != "" {
+			timeout, err = time.ParseDuration(*timeoutPtr)
+			if err != nil {
+				log.Fatal("provided timeout not understood: ", err)
+			}
+		}
+		log.Fatal(serve(*servePtr, cfg, timeout))
+	}
+
+	// a batch gives the cost of each file's solution, rather than the solution itself
+	if *batchPtr != "" {
+		var timeout time.Duration
+		if *timeoutPtr != "" {
+			timeout, err = time.ParseDuration(*timeoutPtr)
+			if err != nil {
+				log.Fatal("provided timeout not understood: ", err)
+			}
+		}
+		results, err := solveBatch(context.Background(), *batchPtr, cfg, timeout)
+		if err != nil {
+			log.Fatal("error reading batch directory: ", err)
+		}
+		for _, result := range results {
+			if result.err != nil {
+				fmt.Fprintf(diagnostics, "Skipping %s: %s", result.file, result.err)
+				fmt.Fprintln(diagnostics)
+				continue
+			}
+			fmt.Printf("%s: cost %g (%.1f%% satisfied)", result.file, result.solution.Cost, result.solution.Satisfaction)
+			fmt.Println()
+		}
+		return
+	}
+
+	var problemContent Problem
+	if *filePtr == "-" || *filePtr == "" {
+		problemContent, err = loadInput(os.Stdin, *formatInPtr, *tablesPtr, *strictPtr)
+		if err != nil {
+			log.Fatal("error reading stdin: ", err)
+		}
+	} else {
+		problemFile, err := os.Open(*filePtr)
+		if err != nil {
+			log.Fatal("error opening file: ", err)
+		}
+		problemContent, err = loadInput(problemFile, *formatInPtr, *tablesPtr, *strictPtr)
+		problemFile.Close()
+		if err != nil {
+			log.Fatal("error making sense of input file: ", err)
+		}
+	}
+
+	// the tables are given in exactly one of the input file (or -tables, for a CSV) and -tablesize
+	switch {
+	case *tableSizePtr != "" && len(problemContent.Tables) > 0:
+		log.Fatal("the tables must be given either in the input file (or with -tables) or with -tablesize, not both")
+	case *tableSizePtr != "":
+		size, err := strconv.Atoi(*tableSizePtr)
+		if err != nil || size <= 0 {
+			log.Fatal("provided table size must be a positive number")
+		}
+		problemContent.Tables = EqualTables(len(problemContent.People), size)
+	case len(problemContent.Tables) == 0:
+		log.Fatal("the tables must be given in the input file, with -tables when reading a CSV, or with -tablesize")
+	}
+
+	if *matrixPtr != "" {
+		matrixFile, err := os.Open(*matrixPtr)
+		if err != nil {
+			log.Fatal("error opening matrix file: ", err)
+		}
+		problemContent.People, err = LoadMatrixCSV(matrixFile, problemContent.People)
+		matrixFile.Close()
+		if err != nil {
+			log.Fatal("error making sense of matrix file: ", err)
+		}
+	}
+
+	if resumed != nil {
+		problemContent.Assignment = resumed.Assignment
+	}
+
+	// names that don't match anyone can never be satisfied, so are most likely misspelt
+	unknown := unknownNames(problemContent)
+	for _, warning := range unknown {
+		fmt.Fprintf(diagnostics, "warning: %s", warning)
+		fmt.Fprintln(diagnostics)
+	}
+	if *strictPtr && len(unknown) > 0 {
+		log.Fatal("input file names people who aren't in it")
+	}
+	self := selfReferences(problemContent)
+	for _, warning := range self {
+		fmt.Fprintf(diagnostics, "warning: %s", warning)
+		fmt.Fprintln(diagnostics)
+	}
+	if *strictPtr && len(self) > 0 {
+		log.Fatal("input file has people who name themselves")
+	}
+
+	// checking stops before annealing, whether or not the input passes
+	if *checkPtr {
+		printCheck(os.Stdout, problemContent)
+		failures := checkProblem(problemContent, cfg)
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "error: %s", failure)
+			fmt.Fprintln(os.Stderr)
+		}
+		if len(failures) > 0 {
+			log.Fatalf("input file failed %d checks", len(failures))
+		}
+		fmt.Println("Input file passed all checks")
+		return
+	}
+
+	if *analyzePtr {
+		printAnalysis(diagnostics, problemContent)
+	}
+
+	if scoreOnly {
+		if flags.NArg() != 1 {
+			log.Fatal("usage: table-allocations score [flags] assignment.json")
+		}
+		o, err := objectiveFor(cfg.Mode)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tables, err := newTables(problemContent)
+		if err != nil {
+			log.Fatal("error making sense of input file: ", err)
+		}
+		s, err := newScoring(problemContent, tables, cfg, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		assignmentRaw, err := ioutil.ReadFile(flags.Arg(0))
+		if err != nil {
+			log.Fatal("error opening assignment file: ", err)
+		}
+		assignment, err := loadAssignment(assignmentRaw, indexPeople(problemContent.People), tables)
+		if err != nil {
+			log.Fatal("error making sense of assignment file: ", err)
+		}
+		printBreakdown(os.Stdout, assignment, s, cfg.Mode, o.cost)
+		return
+	}
+
+	ctx := context.Background()
+	if *timeoutPtr != "" {
+		timeout, err := time.ParseDuration(*timeoutPtr)
+		if err != nil {
+			log.Fatal("provided timeout not understood: ", err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// interrupting the run (e.g. with Ctrl-C) stops it early, as with a timeout - interrupts stop being caught as soon as
+	// it's stopping (however that happens), so that a second interrupt exits as usual while the run winds down
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// record each step to the trace file, as well as printing progress if asked to
+	var traceFile *os.File
+	var trace *csv.Writer
+	if *tracePtr != "" {
+		traceFile, err = os.Create(*tracePtr)
+		if err != nil {
+			log.Fatal("error creating trace file: ", err)
+		}
+		trace = csv.NewWriter(traceFile)
+		trace.Write([]string{"step", "temperature", "bestCost", "acceptedMoves", "rejectedMoves"})
+		printProgress := cfg.Progress
+		cfg.Progress = func(p Progress) {
+			trace.Write([]string{strconv.Itoa(p.Step), strconv.FormatFloat(p.Temperature, 'g', -1, 64), strconv.FormatFloat(p.BestCost, 'g', -1, 64), strconv.Itoa(p.Accepted), strconv.Itoa(p.Rejected)})
+			if printProgress != nil {
+				printProgress(p)
+			}
+		}
+	}
+
+	if cfg.IterationsPerPerson > 0 {
+		fmt.Fprintf(diagnostics, "Using %d iterations at each step (%d for each of %d people)", cfg.Iterations(len(problemContent.People)), cfg.IterationsPerPerson, len(problemContent.People))
+		fmt.Fprintln(diagnostics)
+	}
+	stopProfiling, err := startProfiling(*cpuProfilePtr, *memProfilePtr)
+	if err != nil {
+		log.Fatal("error starting profile: ", err)
+	}
+	solutions, err := SolveRounds(ctx, problemContent, cfg, rounds)
+	stop()
+	// profiles are written before anything else, so that they're kept even if solving failed
+	if profileErr := stopProfiling(); profileErr != nil {
+		log.Fatal("error writing profile: ", profileErr)
+	}
+	if err == context.DeadlineExceeded {
+		fmt.Fprintf(diagnostics, "Timed out after %s, so giving the best solution found so far", *timeoutPtr)
+		fmt.Fprintln(diagnostics)
+	} else if err == context.Canceled {
+		fmt.Fprintln(diagnostics, "Interrupted, so giving the best solution found so far")
+	} else if err != nil {
+		log.Fatal(err)
+	}
+	for round, solution := range solutions {
+		// each round's seeds carry on from the last round's restarts
+		roundSeed := seed + int64(round*runs)
+		if rounds > 1 && ((*reportPtr && *formatPtr == "json") || *summaryPtr != "" || *analyzePtr || *verbosePtr || *autoTempPtr || cfg.Restarts > 1) {
+			fmt.Fprintf(diagnostics, "Round %d:", round+1)
+			fmt.Fprintln(diagnostics)
+		}
+		if *reportPtr && *formatPtr == "json" {
+			printReport(diagnostics, solution.Assignment)
+		}
+		if *summaryPtr != "" {
+			err = printSummary(diagnostics, summariseRestarts(solution.Stats.RestartCosts, roundSeed), *summaryPtr, *prettyPtr)
+			if err != nil {
+				log.Fatal("error writing summary: ", err)
+			}
+		}
+		if *analyzePtr {
+			printBoundFraction(diagnostics, solution.Assignment)
+		}
+		if *verbosePtr {
+			fmt.Fprintf(diagnostics, "Annealed for %d steps (%d cost evaluations) in %.2fs", solution.Stats.Steps, solution.Stats.Evaluations, solution.Stats.ElapsedSeconds)
+			fmt.Fprintln(diagnostics)
+		}
+		if *autoTempPtr {
+			fmt.Fprintf(diagnostics, "Used a base temperature of %g", solution.Stats.BaseTemperature)
+			fmt.Fprintln(diagnostics)
+		}
+		if cfg.Restarts > 1 {
+			for _, restart := range solution.Stats.Rediscovered {
+				fmt.Fprintf(diagnostics, "Restart %d rediscovered a previous solution", restart)
+				fmt.Fprintln(diagnostics)
+			}
+			fmt.Fprintf(diagnostics, "Restart %d (seed %d) found the best solution, with cost %g", solution.Stats.Restart, roundSeed+int64(solution.Stats.Restart), solution.Cost)
+			fmt.Fprintln(diagnostics)
+		}
+	}
+	if trace != nil {
+		trace.Flush()
+		err = trace.Error()
+		if err == nil {
+			err = traceFile.Close()
+		}
+		if err != nil {
+			log.Fatal("error writing trace: ", err)
+		}
+	}
+
+	// write the solution to stdout, or the output file if there is one, buffering so that any error is caught
+	outputFile := os.Stdout
+	if *outputPtr != "" {
+		outputFile, err = os.Create(*outputPtr)
+		if err != nil {
+			log.Fatal("error creating output file: ", err)
+		}
+	}
+	output := bufio.NewWriter(outputFile)
+
+	// with -topk, the distinct solutions are printed in place of the rounds (of which there's only one)
+	printed, heading := solutions, "Round"
+	if cfg.TopK > 1 && len(solutions[0].Top) > 0 {
+		printed, heading = solutions[0].Top, "Solution"
+	}
+	many := rounds > 1 || cfg.TopK > 1
+	switch *formatPtr {
+	case "json":
+		// a single solution is given on its own, as it was before there were rounds
+		var encoded interface{} = printed
+		if !many {
+			encoded = printed[0]
+		}
+		err = newJSONEncoder(output, *prettyPtr).Encode(encoded)
+		if err != nil {
+			log.Fatal("error writing solution: ", err)
+		}
+	case "html":
+		err = printHTML(output, printed, heading)
+		if err != nil {
+			log.Fatal("error writing solution: ", err)
+		}
+	case "dot":
+		// each solution is its own graph
+		for _, solution := range printed {
+			printDot(output, solution.Assignment, solution.Cost)
+		}
+	default:
+		for i, solution := range printed {
+			if many {
+				if i > 0 {
+					fmt.Fprintln(output)
+				}
+				fmt.Fprintf(output, "%s %d", heading, i+1)
+				fmt.Fprintln(output)
+				fmt.Fprintln(output)
+			}
+			// the scoring is only needed to report on the solution, so the problem has already been checked by Solve
+			s, _ := newScoring(problemContent, solution.Assignment, cfg, nil)
+			printSolution(output, solution.Assignment, solution.Cost, s)
+			if *reportPtr {
+				fmt.Fprintln(output)
+				printReport(output, solution.Assignment)
+			}
+		}
+	}
+	err = output.Flush()
+	if err != nil {
+		log.Fatal("error writing solution: ", err)
+	}
+	if *outputPtr != "" {
+		err = outputFile.Close()
+		if err != nil {
+			log.Fatal("error writing solution: ", err)
+		}
+	}
+	if *statsPtr != "" {
+		statsWriter := diagnostics
+		if *statsFilePtr != "" {
+			statsFile, err := os.Create(*statsFilePtr)
+			if err != nil {
+				log.Fatal("error creating stats file: ", err)
+			}
+			defer statsFile.Close()
+			statsWriter = statsFile
+		}
+		for _, solution := range solutions {
+			err = printStats(statsWriter, solution.Stats, *statsPtr, *prettyPtr)
+			if err != nil {
+				log.Fatal("error writing stats: ", err)
+			}
+		}
+	}
+}
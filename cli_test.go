@@ -0,0 +1,79 @@
+package allocations
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// Example_printReport reports on a mix of people who were sat with their preference, weren't, and gave none, where
+// only the one who wasn't counts as lonely
+func Example_printReport() {
+	p := Problem{
+		People: []Person{
+			{Name: "A", Preferences: []Preference{{Name: "B", Weight: 1}}},
+			{Name: "B"},
+			{Name: "C", Preferences: []Preference{{Name: "A", Weight: 1}}},
+			{Name: "D"},
+		},
+		Tables: []TableSpec{{Max: 2}, {Max: 2}},
+	}
+	assignment := seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}})
+	printReport(os.Stdout, assignment)
+	fmt.Println("lonely:", getLonely(assignment))
+	// Output:
+	// C: 0/1 satisfied (missing: A)
+	// A: 1/1 satisfied
+	// B: no preferences given
+	// D: no preferences given
+	//
+	// People who would satisfy more preferences at another table:
+	// Table 0:
+	// - C, from Table 1: 1 more (wants A)
+	// Table 1:
+	// - A, from Table 0: 1 more (wanted by C)
+	// lonely: 1
+}
+
+// Example_printSolution writes the solution and its breakdown to a buffer rather than stdout
+func Example_printSolution() {
+	p := Problem{
+		People: []Person{{Name: "A", Preferences: []Preference{{Name: "B", Weight: 2}}}, {Name: "B"}, {Name: "C"}},
+		Tables: []TableSpec{{Name: "Garden", Max: 2}, {Max: 2}},
+	}
+	assignment := seatProblem(p, [][]string{{"A", "B"}, {"C"}})
+	s := scoringFor(p, Config{})
+	var buffer bytes.Buffer
+	printSolution(&buffer, assignment, sumFunction(assignment, s), s)
+	printBreakdown(&buffer, assignment, s, "sum", sumFunction)
+	fmt.Print(buffer.String())
+	// Output:
+	// Found a solution where 1 people are given a preference (i.e. 2 people have not been allocated at least one of their preferences). 2 preferences are given in total
+	//
+	// Garden (capacity 2)
+	// - A
+	// - B
+	//
+	// Table 1 (capacity 2)
+	// - C
+	// - (empty)
+	//
+	// Final cost 2, with 100.0% of preferences (by weight) satisfied
+	// Cost under the sum cost function: 2
+	// - weight of preferences sat at the same table: 2 of 2
+	// - preferences sat at the same table: 1 of 1
+	// - preferences sat at an adjacent table: 0 (credit 0 each)
+	// - people given a preference: 1 of 3
+	// - plus-ones not sat together: 0
+	// - pairs to be kept apart sat together: 0
+	// - pairs in a group not sat together: 0
+	// - people sat at a table they're forbidden from: 0
+	// - people sat with fewer than 0 of their preferences: 0
+	// - people sat with someone they want to avoid: 0 (penalty 0 each)
+	// - people sat with none of their preferences: 0 (penalty 0 each)
+	// - preferences satisfied for the worst-off person: 1 (for 1 people)
+	// - mutual pairs sat together: 0 (bonus 0 each)
+	// - people sat at a different table from before: 0 (penalty 0 each)
+	// - VIP scores times the desirability of their tables: 0 (weight 0)
+	// - people away from the balanced mix of the attribute: 0 (penalty 0 each)
+}
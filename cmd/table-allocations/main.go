@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	allocations "github.com/mhbardsley/table-allocations"
+)
+
+func main() {
+	allocations.Run(os.Args[1:])
+}
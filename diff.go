@@ -0,0 +1,83 @@
+package allocations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// solutionFile is a solution as printed with -format json (see Solution.MarshalJSON)
+type solutionFile struct {
+	Tables []struct {
+		Name   string   `json:"name"`
+		People []string `json:"people"`
+	} `json:"tables"`
+	Cost            float64 `json:"cost"`
+	SatisfactionPct float64 `json:"satisfactionPct"`
+}
+
+// loadSolutionFile reads a solution printed with -format json
+func loadSolutionFile(filename string) (solutionFile, error) {
+	var solution solutionFile
+	solutionRaw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return solution, err
+	}
+	err = json.Unmarshal(solutionRaw, &solution)
+	return solution, err
+}
+
+// tablesByName returns the name of the table each person is sat at in the solution, along with everyone's names in the
+// order they're sat
+func (s solutionFile) tablesByName() (tables map[string]string, names []string) {
+	tables = make(map[string]string)
+	for _, table := range s.Tables {
+		for _, name := range table.People {
+			tables[name] = table.Name
+			names = append(names, name)
+		}
+	}
+	return tables, names
+}
+
+// printDiff prints where each person is sat before and after (e.g. in an old and a new draft), marking those who moved
+// (or were added or removed), followed by how many moved and the change in cost and satisfaction
+func printDiff(w io.Writer, before solutionFile, after solutionFile) {
+	oldTables, oldNames := before.tablesByName()
+	newTables, newNames := after.tablesByName()
+
+	moved, added, removed := 0, 0, 0
+	for _, name := range newNames {
+		oldTable, ok := oldTables[name]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "+ %s: (new) -> %s", name, newTables[name])
+			added++
+		case oldTable != newTables[name]:
+			fmt.Fprintf(w, "* %s: %s -> %s", name, oldTable, newTables[name])
+			moved++
+		default:
+			fmt.Fprintf(w, "  %s: %s", name, oldTable)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, name := range oldNames {
+		if _, ok := newTables[name]; !ok {
+			fmt.Fprintf(w, "- %s: %s -> (removed)", name, oldTables[name])
+			fmt.Fprintln(w)
+			removed++
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%d of %d people moved", moved, len(newNames)-added)
+	if added > 0 || removed > 0 {
+		fmt.Fprintf(w, " (%d added, %d removed)", added, removed)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Cost: %g -> %g (%+g)", before.Cost, after.Cost, after.Cost-before.Cost)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Satisfaction: %.1f%% -> %.1f%% (%+.1f%%)", before.SatisfactionPct, after.SatisfactionPct, after.SatisfactionPct-before.SatisfactionPct)
+	fmt.Fprintln(w)
+}
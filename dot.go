@@ -0,0 +1,70 @@
+package allocations
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotQuoter escapes names for use as quoted DOT strings
+var dotQuoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// dotQuote returns the string as a quoted DOT string
+func dotQuote(s string) string {
+	return `"` + dotQuoter.Replace(s) + `"`
+}
+
+// printDot prints the solution as a DOT graph (e.g. for dot -Tpng), with people clustered by table. Each preference is
+// an edge from the person to the person they prefer: green when they're sat together, and dashed grey when they're not.
+// People sat with someone they want to avoid have a red edge to them
+func printDot(w io.Writer, solution []table, cost float64) {
+	fmt.Fprintln(w, "digraph seating {")
+	fmt.Fprintf(w, "\tlabel=%s;", dotQuote(fmt.Sprintf("Cost %g, with %.1f%% of preferences (by weight) satisfied", cost, getSatisfaction(solution))))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	for tableNo, table := range solution {
+		fmt.Fprintf(w, "\tsubgraph cluster_%d {", tableNo)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "\t\tlabel=%s;", dotQuote(fmt.Sprintf("%s (capacity %d)", table.name, table.capacity)))
+		fmt.Fprintln(w)
+		for _, person := range inInputOrder(table) {
+			fmt.Fprintf(w, "\t\tp%d [label=%s];", person.id, dotQuote(person.Name))
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+
+	for tableNo, table := range solution {
+		for _, person := range inInputOrder(table) {
+			for otherNo, other := range solution {
+				for _, sat := range inInputOrder(other) {
+					if sat.id == person.id {
+						continue
+					}
+					switch {
+					case otherNo == tableNo && avoids(person, sat):
+						fmt.Fprintf(w, "\tp%d -> p%d [color=red, penwidth=2];", person.id, sat.id)
+					case !prefersPerson(person, sat):
+						continue
+					case otherNo == tableNo:
+						fmt.Fprintf(w, "\tp%d -> p%d [color=green];", person.id, sat.id)
+					default:
+						fmt.Fprintf(w, "\tp%d -> p%d [color=grey, style=dashed, constraint=false];", person.id, sat.id)
+					}
+					fmt.Fprintln(w)
+				}
+			}
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// avoids returns whether the person wants to avoid the other
+func avoids(person Person, other Person) bool {
+	for _, id := range person.avoidIDs {
+		if id == other.id {
+			return true
+		}
+	}
+	return false
+}
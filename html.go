@@ -0,0 +1,72 @@
+package allocations
+
+import (
+	"html/template"
+	"io"
+)
+
+// htmlTemplate is a printable page with a card for each table, after a summary of how many preferences were satisfied
+var htmlTemplate = template.Must(template.New("seating").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Seating plan</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.tables { display: flex; flex-wrap: wrap; gap: 1em; }
+.table { border: 1px solid #888; border-radius: 6px; padding: 0.5em 1em; min-width: 12em; break-inside: avoid; }
+.table h3 { margin: 0.3em 0; }
+.capacity { color: #666; font-size: 0.9em; }
+ul { padding-left: 1.2em; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+{{range .}}<section>
+{{if .Number}}<h1>{{.Heading}} {{.Number}}</h1>
+{{end}}<h2>{{.Given}} of {{.People}} people sat with at least one of their preferences, with {{printf "%.1f" .Satisfaction}}% of preferences (by weight) satisfied</h2>
+<div class="tables">
+{{range .Tables}}<div class="table">
+<h3>{{.Name}}</h3>
+<div class="capacity">{{len .People}} of {{.Capacity}} seats{{if and .Minimum (lt .Minimum .Capacity)}} (at least {{.Minimum}}){{end}}</div>
+<ul>
+{{range .People}}<li>{{.}}</li>
+{{end}}</ul>
+</div>
+{{end}}</div>
+</section>
+{{end}}</body>
+</html>
+`))
+
+// printHTML prints the solutions (e.g. one for each round) as a printable HTML page, where each is headed with the
+// given heading and its number if there's more than one
+func printHTML(w io.Writer, solutions []Solution, heading string) error {
+	type htmlTable struct {
+		Name              string
+		Capacity, Minimum int
+		People            []string
+	}
+	type htmlSolution struct {
+		Heading               string
+		Number, Given, People int
+		Satisfaction          float64
+		Tables                []htmlTable
+	}
+
+	// only preferences at the same table are counted, as in the text output
+	pages := make([]htmlSolution, len(solutions))
+	for i, solution := range solutions {
+		page := htmlSolution{Satisfaction: solution.Satisfaction, People: getNoOfPeople(solution.Assignment)}
+		if len(solutions) > 1 {
+			page.Heading, page.Number = heading, i+1
+		}
+		page.Given = int(countFunction(solution.Assignment, scoring{}))
+		for tableNo, names := range solution.Tables() {
+			table := solution.Assignment[tableNo]
+			page.Tables = append(page.Tables, htmlTable{Name: table.name, Capacity: table.capacity, Minimum: table.minimum, People: names})
+		}
+		pages[i] = page
+	}
+	return htmlTemplate.Execute(w, pages)
+}
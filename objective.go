@@ -0,0 +1,217 @@
+package allocations
+
+import (
+	"fmt"
+	"math"
+)
+
+// objective is what the annealer maximises, chosen by the mode
+type objective interface {
+	cost(assignment []table, s scoring) float64
+	maxPossible(assignment []table, s scoring) float64 // the cost if every preference in the assignment were satisfied
+}
+
+// partsObjective is an objective that can be given from the parts of the cost from each table (see costParts), so that
+// it can be updated a table at a time as people are swapped
+type partsObjective interface {
+	objective
+	tableParts(assignment []table, tableNo int, s scoring) costParts
+	fromParts(c costParts, highest float64) float64
+}
+
+// CostFunction scores a solution for Solve to maximise in place of the mode, given the people sat at each table in
+// order (leaving out empty seats)
+type CostFunction interface {
+	Cost(tables [][]Person) float64
+}
+
+// TableCostFunction is a CostFunction whose cost is the sum of a cost for each table, given the people sat at it. As
+// swapping people only changes the cost of the tables they're swapped between, the rest don't need to be scored again
+type TableCostFunction interface {
+	CostFunction
+	TableCost(people []Person) float64
+}
+
+// objectiveFor returns the objective for the given mode
+func objectiveFor(mode string) (objective, error) {
+	switch mode {
+	case "hybrid":
+		return hybridObjective{}, nil
+	case "sum":
+		return sumObjective{}, nil
+	case "count":
+		return countObjective{}, nil
+	case "maximin":
+		return maximinObjective{}, nil
+	default:
+		return nil, fmt.Errorf("cost function %q not understood", mode)
+	}
+}
+
+// objectiveOf returns the objective maximising the given cost function, which can be given from its parts if it is
+// the sum of a cost for each table
+func objectiveOf(f CostFunction) objective {
+	if t, ok := f.(TableCostFunction); ok {
+		return tableCostObjective{t}
+	}
+	return costFunctionObjective{f}
+}
+
+// personScored gives each table's part of the cost from the people sat at it, for the objectives built on them
+type personScored struct{}
+
+func (personScored) tableParts(assignment []table, tableNo int, s scoring) costParts {
+	return tableParts(assignment, tableNo, s)
+}
+
+// sumObjective maximises the total weight of satisfied preferences
+type sumObjective struct{ personScored }
+
+func (sumObjective) cost(assignment []table, s scoring) float64 {
+	return sumFunction(assignment, s)
+}
+
+func (sumObjective) fromParts(c costParts, highest float64) float64 {
+	return sumOfParts(c, highest)
+}
+
+func (sumObjective) maxPossible(assignment []table, s scoring) float64 {
+	return getHighestSum(assignment, s)
+}
+
+// countObjective maximises the number of people with at least one satisfied preference
+type countObjective struct{ personScored }
+
+func (countObjective) cost(assignment []table, s scoring) float64 {
+	return countFunction(assignment, s)
+}
+
+func (countObjective) fromParts(c costParts, highest float64) float64 {
+	return countOfParts(c, highest)
+}
+
+func (countObjective) maxPossible(assignment []table, s scoring) float64 {
+	return float64(getNoOfPeople(assignment))
+}
+
+// hybridObjective maximises the count, with the sum breaking any ties
+type hybridObjective struct{ personScored }
+
+func (hybridObjective) cost(assignment []table, s scoring) float64 {
+	return hybridFunction(assignment, s)
+}
+
+func (hybridObjective) fromParts(c costParts, highest float64) float64 {
+	return hybridOfParts(c, highest)
+}
+
+func (hybridObjective) maxPossible(assignment []table, s scoring) float64 {
+	noOfPeople := float64(getNoOfPeople(assignment))
+	highestSum := getHighestSum(assignment, s)
+	return noOfPeople*math.Max(noOfPeople, highestSum) + highestSum
+}
+
+// maximinObjective maximises the number of preferences satisfied for the worst-off person (out of those who have any),
+// then minimises how many people are that badly off (so that the annealer can work its way up to the next number), with
+// the sum breaking any ties. The worst-off person can change with any swap, so it can't be given from its parts
+type maximinObjective struct{}
+
+func (maximinObjective) cost(assignment []table, s scoring) float64 {
+	worst, worstOff := getWorstOff(assignment)
+	return float64(worst)*s.worstOffScale*float64(getNoOfPeople(assignment)+1) - float64(worstOff)*s.worstOffScale + sumFunction(assignment, s)
+}
+
+func (maximinObjective) maxPossible(assignment []table, s scoring) float64 {
+	fewest := -1
+	for _, table := range assignment {
+		for _, person := range table.people {
+			if !person.empty && wanted(person) > 0 && (fewest == -1 || wanted(person) < fewest) {
+				fewest = wanted(person)
+			}
+		}
+	}
+	if fewest == -1 {
+		fewest = 0
+	}
+	return float64(fewest)*s.worstOffScale*float64(getNoOfPeople(assignment)+1) + getHighestSum(assignment, s)
+}
+
+// getWorstOff returns the fewest preferences satisfied for anyone in the assignment who has any, and how many people
+// have that few
+func getWorstOff(assignment []table) (worst int, worstOff int) {
+	worst = -1
+	for _, table := range assignment {
+		for _, person := range table.people {
+			if person.empty || wanted(person) == 0 {
+				continue
+			}
+			satisfied := satisfiedAtTable(table, person)
+			if worst == -1 || satisfied < worst {
+				worst, worstOff = satisfied, 0
+			}
+			if satisfied == worst {
+				worstOff++
+			}
+		}
+	}
+	if worst == -1 {
+		return 0, 0
+	}
+	return worst, worstOff
+}
+
+// costFunctionObjective maximises a cost function given from outside the package
+type costFunctionObjective struct {
+	f CostFunction
+}
+
+func (o costFunctionObjective) cost(assignment []table, s scoring) float64 {
+	return o.f.Cost(seatedPeople(assignment))
+}
+
+// the most a cost function could give isn't known, so it's given as 0
+func (costFunctionObjective) maxPossible(assignment []table, s scoring) float64 {
+	return 0
+}
+
+// tableCostObjective maximises a table cost function given from outside the package, where each table's part of the
+// cost is its table cost
+type tableCostObjective struct {
+	f TableCostFunction
+}
+
+func (o tableCostObjective) cost(assignment []table, s scoring) float64 {
+	return o.f.Cost(seatedPeople(assignment))
+}
+
+func (o tableCostObjective) tableParts(assignment []table, tableNo int, s scoring) costParts {
+	return costParts{sum: o.f.TableCost(seatedAt(assignment[tableNo]))}
+}
+
+func (tableCostObjective) fromParts(c costParts, highest float64) float64 {
+	return c.sum
+}
+
+func (tableCostObjective) maxPossible(assignment []table, s scoring) float64 {
+	return 0
+}
+
+// seatedPeople returns the people sat at each table in the assignment
+func seatedPeople(assignment []table) [][]Person {
+	people := make([][]Person, len(assignment))
+	for i, table := range assignment {
+		people[i] = seatedAt(table)
+	}
+	return people
+}
+
+// seatedAt returns the people sat at the table, leaving out empty seats
+func seatedAt(t table) []Person {
+	people := make([]Person, 0, len(t.people))
+	for _, person := range t.people {
+		if !person.empty {
+			people = append(people, person)
+		}
+	}
+	return people
+}
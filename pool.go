@@ -0,0 +1,122 @@
+package allocations
+
+import "sort"
+
+// solutionPool keeps the best solutions seen that are distinct from each other, for giving several options to choose
+// from rather than just the best. A nil pool keeps nothing
+type solutionPool struct {
+	size     int     // the most solutions kept
+	distinct float64 // the fraction of people who must be sat at a different table for two solutions to be distinct (see solutionSimilarity)
+	entries  []poolEntry
+}
+
+type poolEntry struct {
+	assignment []table
+	cost       float64
+}
+
+// newSolutionPool returns a pool keeping up to size solutions, where each differs from the others in where at least
+// the given fraction of people are sat
+func newSolutionPool(size int, distinct float64) *solutionPool {
+	return &solutionPool{size: size, distinct: distinct}
+}
+
+// offer adds the solution to the pool (copying it) if it is better than a solution it's too similar to, or is distinct
+// from them all and there's room for it or it beats the worst
+func (p *solutionPool) offer(assignment []table, cost float64) {
+	if p == nil {
+		return
+	}
+	for i, entry := range p.entries {
+		if 1-solutionSimilarity(assignment, entry.assignment) < p.distinct {
+			if cost > entry.cost {
+				p.entries[i] = poolEntry{copyAssignment(assignment), cost}
+				p.sort()
+			}
+			return
+		}
+	}
+	switch {
+	case len(p.entries) < p.size:
+		p.entries = append(p.entries, poolEntry{copyAssignment(assignment), cost})
+	case cost > p.entries[len(p.entries)-1].cost:
+		p.entries[len(p.entries)-1] = poolEntry{copyAssignment(assignment), cost}
+	default:
+		return
+	}
+	p.sort()
+}
+
+// sort puts the pool in order of cost, best first
+func (p *solutionPool) sort() {
+	sort.SliceStable(p.entries, func(i, j int) bool {
+		return p.entries[i].cost > p.entries[j].cost
+	})
+}
+
+// solutions returns the solutions in the pool, best first
+func (p *solutionPool) solutions() []Solution {
+	if p == nil {
+		return nil
+	}
+	solutions := make([]Solution, len(p.entries))
+	for i, entry := range p.entries {
+		solutions[i] = Solution{Assignment: entry.assignment, Cost: entry.cost, Satisfaction: getSatisfaction(entry.assignment)}
+	}
+	return solutions
+}
+
+// solutionSimilarity returns the fraction of people sat at the same table in the two solutions, which must seat the
+// same people. Tables that are interchangeable (see interchangeable) can be matched up with each other rather than
+// only by their index, so that the same seating with its tables the other way round still counts as the same - they're
+// matched greedily, those with the most people in common first
+func solutionSimilarity(a []table, b []table) float64 {
+	people := 0
+	common := make([][]int, len(a))
+	for i, table := range a {
+		common[i] = make([]int, len(b))
+		for _, person := range table.people {
+			if person.empty {
+				continue
+			}
+			people++
+			for j := range b {
+				if b[j].has(person.id) {
+					common[i][j]++
+				}
+			}
+		}
+	}
+	if people == 0 {
+		return 1
+	}
+
+	type match struct{ i, j int }
+	var matches []match
+	for i := range a {
+		for j := range b {
+			if i == j || interchangeable(a[i], a[j]) {
+				matches = append(matches, match{i, j})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(x, y int) bool {
+		return common[matches[x].i][matches[x].j] > common[matches[y].i][matches[y].j]
+	})
+	usedA, usedB := make([]bool, len(a)), make([]bool, len(b))
+	same := 0
+	for _, m := range matches {
+		if !usedA[m.i] && !usedB[m.j] {
+			usedA[m.i], usedB[m.j] = true, true
+			same += common[m.i][m.j]
+		}
+	}
+	return float64(same) / float64(people)
+}
+
+// interchangeable reports whether swapping everyone at the two tables would give an equivalent seating, as they're
+// the same size and as desirable, with nobody pinned to them, no tables next to them and nothing else that refers to
+// them by their index (see table.anchored)
+func interchangeable(a table, b table) bool {
+	return a.capacity == b.capacity && a.minimum == b.minimum && a.desirability == b.desirability && a.pinned == 0 && b.pinned == 0 && len(a.adjacent) == 0 && len(b.adjacent) == 0 && !a.anchored && !b.anchored
+}
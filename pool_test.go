@@ -0,0 +1,56 @@
+package allocations
+
+import "fmt"
+
+// seatProblem returns the tables of the problem with the named people sat at each, set up as solve would
+func seatProblem(p Problem, names [][]string) []table {
+	tables, err := newTables(p)
+	if err != nil {
+		panic(err)
+	}
+	_, err = newScoring(p, tables, Config{}, nil)
+	if err != nil {
+		panic(err)
+	}
+	people := indexPeople(p.People)
+	p.People = people
+	_, err = pinPeople(p, tables)
+	if err != nil {
+		panic(err)
+	}
+	assignment, err := seatNames(names, people, tables)
+	if err != nil {
+		panic(err)
+	}
+	return assignment
+}
+
+func Example_solutionSimilarity() {
+	p := Problem{
+		People: []Person{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}},
+		Tables: []TableSpec{{Max: 2}, {Max: 2}},
+	}
+	first := seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}})
+	fmt.Println("identical:", solutionSimilarity(first, seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}})))
+	fmt.Println("tables swapped:", solutionSimilarity(first, seatProblem(p, [][]string{{"C", "D"}, {"A", "B"}})))
+	fmt.Println("partial:", solutionSimilarity(first, seatProblem(p, [][]string{{"A", "C"}, {"B", "D"}})))
+
+	// tables that aren't interchangeable, with everyone moved to another one
+	p.Tables = []TableSpec{{Max: 2, Desirability: 1}, {Max: 2}}
+	fmt.Println("disjoint:", solutionSimilarity(seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}}), seatProblem(p, [][]string{{"C", "D"}, {"A", "B"}})))
+
+	// once something refers to a table by its index, swapping it with another changes the seating
+	p.Tables = []TableSpec{{Max: 2}, {Max: 2}}
+	p.Forbid = map[string][]int{"A": {1}}
+	fmt.Println("forbidden table swapped:", solutionSimilarity(seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}}), seatProblem(p, [][]string{{"C", "D"}, {"A", "B"}})))
+	p.Forbid = nil
+	p.Tables = []TableSpec{{Name: "Top", Max: 2}, {Max: 2}}
+	fmt.Println("named table swapped:", solutionSimilarity(seatProblem(p, [][]string{{"A", "B"}, {"C", "D"}}), seatProblem(p, [][]string{{"C", "D"}, {"A", "B"}})))
+	// Output:
+	// identical: 1
+	// tables swapped: 1
+	// partial: 0.5
+	// disjoint: 0
+	// forbidden table swapped: 0
+	// named table swapped: 0
+}
@@ -0,0 +1,50 @@
+package allocations
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiling starts a CPU profile written to cpuFile (if given), and returns a function to call once the work being
+// profiled is done, which stops it and writes a heap profile to memFile (if given). The files are always closed, even if
+// writing either profile fails
+func startProfiling(cpuFile string, memFile string) (stop func() error, err error) {
+	var cpu *os.File
+	if cpuFile != "" {
+		cpu, err = os.Create(cpuFile)
+		if err != nil {
+			return nil, err
+		}
+		err = pprof.StartCPUProfile(cpu)
+		if err != nil {
+			cpu.Close()
+			return nil, err
+		}
+	}
+
+	return func() error {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			err := cpu.Close()
+			if err != nil {
+				return err
+			}
+		}
+		if memFile == "" {
+			return nil
+		}
+		mem, err := os.Create(memFile)
+		if err != nil {
+			return err
+		}
+		// a garbage collection first gives up-to-date statistics on what's still allocated
+		runtime.GC()
+		err = pprof.WriteHeapProfile(mem)
+		closeErr := mem.Close()
+		if err != nil {
+			return err
+		}
+		return closeErr
+	}, nil
+}
@@ -0,0 +1,122 @@
+package allocations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// the largest problem a request's body can hold, so that a client can't make the server read without end
+const maxProblemBytes = 10 << 20
+
+// serve answers POST /solve requests on the given address until it fails, solving the problem in each request's body
+// with the given configuration. The mode, iterations, restarts, seed and timeout can be given for each request in the
+// query string, with the same names as their flags, e.g. /solve?m=sum&timeout=10s - the seed is otherwise picked from
+// the time, and the timeout otherwise the one given. Problems that can't be solved as they stand are bad requests, while
+// anything else that stops one being solved is the server's own error
+func serve(addr string, cfg Config, timeout time.Duration) error {
+	// the callbacks are for a single run from the command line, rather than many at once
+	cfg.Progress = nil
+	cfg.Checkpoint = nil
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/solve", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requestCfg, requestTimeout, err := requestConfig(r, cfg, timeout)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		problem, err := LoadProblem(http.MaxBytesReader(w, r.Body, maxProblemBytes))
+		if err != nil {
+			http.Error(w, "error making sense of problem: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// running out of time still gives the best solution found so far, as on the command line
+		ctx := r.Context()
+		if requestTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+			defer cancel()
+		}
+		solution, err := Solve(ctx, problem, requestCfg)
+		if err == context.Canceled {
+			return
+		}
+		var inProblem problemError
+		if errors.As(err, &inProblem) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil && err != context.DeadlineExceeded {
+			log.Print("error solving problem: ", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(solution)
+		if err != nil {
+			log.Print("error writing solution: ", err)
+		}
+	})
+	return http.ListenAndServe(addr, mux)
+}
+
+// requestConfig returns the configuration and timeout for a request, from any given in its query string, checking that
+// what it gives can be solved with
+func requestConfig(r *http.Request, cfg Config, timeout time.Duration) (Config, time.Duration, error) {
+	query := r.URL.Query()
+	cfg.Seed = time.Now().UnixNano()
+	if m := query.Get("m"); m != "" {
+		_, err := objectiveFor(m)
+		if err != nil {
+			return cfg, 0, err
+		}
+		cfg.Mode = m
+	}
+	if i := query.Get("i"); i != "" {
+		iterations, err := strconv.Atoi(i)
+		if err != nil {
+			return cfg, 0, err
+		}
+		cfg.InternalIterations = iterations
+		cfg.IterationsPerPerson = 0
+	}
+	if restarts := query.Get("restarts"); restarts != "" {
+		var err error
+		cfg.Restarts, err = strconv.Atoi(restarts)
+		if err != nil {
+			return cfg, 0, err
+		}
+		if cfg.Restarts < 0 {
+			return cfg, 0, fmt.Errorf("restarts must not be negative, but is %d", cfg.Restarts)
+		}
+	}
+	if seed := query.Get("seed"); seed != "" {
+		var err error
+		cfg.Seed, err = strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			return cfg, 0, err
+		}
+	}
+	if t := query.Get("timeout"); t != "" {
+		var err error
+		timeout, err = time.ParseDuration(t)
+		if err != nil {
+			return cfg, 0, err
+		}
+	}
+	return cfg, timeout, cfg.Validate()
+}
@@ -0,0 +1,24 @@
+package allocations
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"time"
+)
+
+func Example_requestConfig() {
+	cfg := benchmarkConfig
+	cfg.IterationsPerPerson = 20
+	requestCfg, timeout, err := requestConfig(httptest.NewRequest("POST", "/solve?i=50&m=count&timeout=10s", nil), cfg, time.Minute)
+	fmt.Println(requestCfg.Iterations(100), requestCfg.Mode, timeout, err)
+
+	for _, query := range []string{"m=best", "i=0", "restarts=-1"} {
+		_, _, err = requestConfig(httptest.NewRequest("POST", "/solve?"+query, nil), cfg, time.Minute)
+		fmt.Println(err)
+	}
+	// Output:
+	// 50 count 10s <nil>
+	// cost function "best" not understood
+	// internal iterations must be positive, but is 0
+	// restarts must not be negative, but is -1
+}
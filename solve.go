@@ -0,0 +1,1263 @@
+package allocations
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Problem is a table allocation problem: the people to seat, the capacities of the tables to seat them at and any
+// constraints on who sits together
+type Problem struct {
+	People         []Person         `json:"people"`
+	Tables         []TableSpec      `json:"tables"`
+	PlusOnes       []PlusOne        `json:"plusOnes"`
+	AdjacentTables [][2]int         `json:"adjacentTables"` // pairs of table indexes that are next to each other
+	Pinned         map[string]int   `json:"pinned"`         // people who must be sat at a particular table, by its index
+	Apart          [][2]string      `json:"apart"`          // pairs of people who must not be sat at the same table
+	Groups         [][]string       `json:"groups"`         // groups of people who must all be sat at the same table
+	Forbid         map[string][]int `json:"forbid"`         // people who must not be sat at particular tables, by their indexes
+	Previous       [][]string       `json:"previous"`       // if given, the names sat at each table before, for Config.StabilityWeight
+	Assignment     [][]string       `json:"assignment"`     // if given, the names sat at each table to start from
+	Edges          []Edge           `json:"edges"`          // pairs who would like to sit together, which are added to both people's preferences
+}
+
+// Edge is a pair of people who would both like to sit with each other, weighted by how much it matters to them
+type Edge struct {
+	Names  [2]string
+	Weight float64
+}
+
+// UnmarshalJSON accepts a pair of names, which is given a weight of 1, or a pair of names followed by a weight
+func (e *Edge) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+	if len(fields) != 2 && len(fields) != 3 {
+		return fmt.Errorf("edge should be two names and an optional weight, but has %d fields", len(fields))
+	}
+
+	edge := Edge{Weight: 1}
+	for i := range edge.Names {
+		err = json.Unmarshal(fields[i], &edge.Names[i])
+		if err != nil {
+			return err
+		}
+	}
+	if len(fields) == 3 {
+		err = json.Unmarshal(fields[2], &edge.Weight)
+		if err != nil {
+			return err
+		}
+	}
+	*e = edge
+	return nil
+}
+
+// TableSpec is a table's name (if it has one) and how many people it seats - at most Max, and at least Min. Its
+// desirability is how good a table it is to be sat at (e.g. near the stage), for the VIPs
+type TableSpec struct {
+	Name         string   `json:"name"`
+	Min          int      `json:"min"`
+	Max          int      `json:"max"`
+	Desirability float64  `json:"desirability"`
+	Fixed        []string `json:"fixed"` // the people already sat at the table, who are only listed here (see addFixed)
+}
+
+// UnmarshalJSON accepts either a bare number, which is a table seating exactly that many people (so is both its
+// minimum and its maximum), or an object with an optional name and either a capacity or a min and max. A table that
+// can leave seats empty is given as an object, e.g. {"max": 10}
+func (t *TableSpec) UnmarshalJSON(data []byte) error {
+	return t.unmarshal(data, false)
+}
+
+// unmarshal reads the table as UnmarshalJSON does, only allowing known fields in an object if strict
+func (t *TableSpec) unmarshal(data []byte, strict bool) error {
+	var capacity int
+	if json.Unmarshal(data, &capacity) == nil {
+		*t = TableSpec{Min: capacity, Max: capacity}
+		return nil
+	}
+
+	var spec struct {
+		Name         string   `json:"name"`
+		Capacity     int      `json:"capacity"`
+		Min          int      `json:"min"`
+		Max          int      `json:"max"`
+		Desirability float64  `json:"desirability"`
+		Fixed        []string `json:"fixed"`
+	}
+	err := decodeJSON(data, &spec, strict)
+	if err != nil {
+		return err
+	}
+	if spec.Max == 0 {
+		spec.Max = spec.Capacity
+	}
+	*t = TableSpec{Name: spec.Name, Min: spec.Min, Max: spec.Max, Desirability: spec.Desirability, Fixed: spec.Fixed}
+	return nil
+}
+
+// EqualTables returns as many tables of the given size as are needed to seat the given number of people, where any
+// seats not needed are left empty (wherever the solution puts them)
+func EqualTables(people int, size int) []TableSpec {
+	tables := make([]TableSpec, (people+size-1)/size)
+	for i := range tables {
+		tables[i] = TableSpec{Max: size}
+	}
+	return tables
+}
+
+// LoadProblem reads a problem from its JSON, as in the input file
+func LoadProblem(r io.Reader) (Problem, error) {
+	return loadProblem(r, false)
+}
+
+// LoadProblemStrict reads a problem from its JSON as LoadProblem does, but returns an error for any field it doesn't
+// know (e.g. a misspelling of "people"), rather than ignoring it
+func LoadProblemStrict(r io.Reader) (Problem, error) {
+	return loadProblem(r, true)
+}
+
+// loadProblem reads a problem from its JSON, only allowing known fields if strict
+func loadProblem(r io.Reader, strict bool) (Problem, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Problem{}, err
+	}
+	var p Problem
+	err = decodeJSON(data, &p, strict)
+	if err != nil {
+		return Problem{}, err
+	}
+	if strict {
+		err = checkFields(data)
+		if err != nil {
+			return Problem{}, err
+		}
+	}
+	err = addFixed(&p)
+	if err != nil {
+		return Problem{}, err
+	}
+	err = addEdges(&p)
+	if err != nil {
+		return Problem{}, err
+	}
+	return p, nil
+}
+
+// decodeJSON unmarshals the JSON into v as json.Unmarshal does, but returns an error for any field v doesn't have if
+// strict
+func decodeJSON(data []byte, v interface{}, strict bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(v)
+}
+
+// checkFields returns an error for any field the problem's tables and preferences don't know. A decoder that disallows
+// unknown fields doesn't pass that on to types with their own UnmarshalJSON, so these are read again strictly
+func checkFields(data []byte) error {
+	var parts struct {
+		People []struct {
+			Name        string            `json:"name"`
+			Preferences []json.RawMessage `json:"preferences"`
+		} `json:"people"`
+		Tables []json.RawMessage `json:"tables"`
+	}
+	err := json.Unmarshal(data, &parts)
+	if err != nil {
+		return err
+	}
+	for i, raw := range parts.Tables {
+		var spec TableSpec
+		err = spec.unmarshal(raw, true)
+		if err != nil {
+			return fmt.Errorf("table %d: %w", i, err)
+		}
+	}
+	for _, person := range parts.People {
+		for _, raw := range person.Preferences {
+			var preference Preference
+			err = preference.unmarshal(raw, true)
+			if err != nil {
+				return fmt.Errorf("a preference of %s: %w", person.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expandProblem returns the problem with the people fixed at its tables and its edges added, as loadProblem does when
+// reading it, so that they count however the problem was made. What's added to is copied first, leaving the caller's
+// problem as it was
+func expandProblem(p Problem) (Problem, error) {
+	p.People = append([]Person(nil), p.People...)
+	for i := range p.People {
+		p.People[i].Preferences = append([]Preference(nil), p.People[i].Preferences...)
+	}
+	p.Tables = append([]TableSpec(nil), p.Tables...)
+	pinned := p.Pinned
+	p.Pinned = make(map[string]int, len(pinned))
+	for name, tableNo := range pinned {
+		p.Pinned[name] = tableNo
+	}
+	err := addFixed(&p)
+	if err != nil {
+		return Problem{}, err
+	}
+	err = addEdges(&p)
+	if err != nil {
+		return Problem{}, err
+	}
+	return p, nil
+}
+
+// addFixed adds the people fixed at each table (who have no preferences of their own) to the problem, pinned to that
+// table, and then clears them so that they're only added once. Fixed people mustn't also be listed in the people or
+// pinned, nor fixed at more than one table, and a table can't have more fixed people than it seats
+func addFixed(p *Problem) error {
+	ids := personIDs(p.People)
+	fixedAt := make(map[string]int)
+	for tableNo, spec := range p.Tables {
+		if len(spec.Fixed) > 0 && len(spec.Fixed) > spec.Max {
+			return fmt.Errorf("table %d has %d people fixed at it, but only seats %d", tableNo, len(spec.Fixed), spec.Max)
+		}
+		for _, name := range spec.Fixed {
+			if lookupID(ids, name) != -1 {
+				return fmt.Errorf("%s is fixed at table %d, so must not also be listed in the people", name, tableNo)
+			}
+			if other, fixed := fixedAt[name]; fixed {
+				return fmt.Errorf("%s is fixed at both table %d and table %d", name, other, tableNo)
+			}
+			if _, pinned := p.Pinned[name]; pinned {
+				return fmt.Errorf("%s is fixed at table %d, so must not also be pinned", name, tableNo)
+			}
+			fixedAt[name] = tableNo
+		}
+	}
+
+	for tableNo := range p.Tables {
+		for _, name := range p.Tables[tableNo].Fixed {
+			if p.Pinned == nil {
+				p.Pinned = make(map[string]int)
+			}
+			p.People = append(p.People, Person{Name: name})
+			p.Pinned[name] = tableNo
+		}
+		p.Tables[tableNo].Fixed = nil
+	}
+	return nil
+}
+
+// addEdges adds each of the problem's edges to the preferences of both people in it, which must both be in the
+// problem, and then clears them so that they're only added once
+func addEdges(p *Problem) error {
+	ids := personIDs(p.People)
+	for i, edge := range p.Edges {
+		for _, name := range edge.Names {
+			if lookupID(ids, name) == -1 {
+				return fmt.Errorf("edge %d names %q, who isn't in the problem", i, name)
+			}
+		}
+	}
+
+	for _, edge := range p.Edges {
+		for end, name := range edge.Names {
+			person := &p.People[ids[name]]
+			person.Preferences = append(person.Preferences, Preference{Name: edge.Names[1-end], Weight: edge.Weight})
+		}
+	}
+	p.Edges = nil
+	return nil
+}
+
+// LoadPeopleCSV reads people from a CSV with a header row naming a name and a preferences column, where preferences
+// are separated by semicolons (any other columns are ignored)
+func LoadPeopleCSV(r io.Reader) ([]Person, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // spreadsheets often leave out empty trailing columns
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	nameColumn, preferencesColumn := -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "name":
+			nameColumn = i
+		case "preferences":
+			preferencesColumn = i
+		}
+	}
+	if nameColumn == -1 || preferencesColumn == -1 {
+		return nil, fmt.Errorf("line 1: header needs a name and a preferences column")
+	}
+
+	var people []Person
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return people, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if nameColumn >= len(record) || strings.TrimSpace(record[nameColumn]) == "" {
+			return nil, fmt.Errorf("line %d: missing name", line)
+		}
+		person := Person{Name: strings.TrimSpace(record[nameColumn])}
+		if preferencesColumn < len(record) {
+			for _, name := range strings.Split(record[preferencesColumn], ";") {
+				if name = strings.TrimSpace(name); name != "" {
+					person.Preferences = append(person.Preferences, Preference{Name: name, Weight: 1})
+				}
+			}
+		}
+		people = append(people, person)
+	}
+}
+
+// LoadMatrixCSV reads a matrix of how much each person wants to be sat with each other person, and returns a copy of
+// the people with it added to their preferences. The header row names the people (in any order), and the row after it
+// for each of them, in the same order, gives the benefit (if positive) or penalty (if negative) of sitting them with
+// each of the people in the header - so the cell in row i and column j is person i's preference for person j. The
+// matrix must be square, with a row and column for each of the people. Cells on the diagonal, and cells of 0, are
+// ignored
+func LoadMatrixCSV(r io.Reader, people []Person) ([]Person, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("line 1: header needs the name of each person")
+	}
+	names := records[0]
+	if len(names) != len(people) {
+		return nil, fmt.Errorf("line 1: header names %d people, but there are %d in the problem", len(names), len(people))
+	}
+	if len(records)-1 != len(names) {
+		return nil, fmt.Errorf("matrix must be square, but has %d rows for %d people", len(records)-1, len(names))
+	}
+	ids := personIDs(people)
+	seen := make(map[string]bool)
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if lookupID(ids, names[i]) == -1 {
+			return nil, fmt.Errorf("line 1: %s isn't in the problem", names[i])
+		}
+		if seen[names[i]] {
+			return nil, fmt.Errorf("line 1: %s is named more than once", names[i])
+		}
+		seen[names[i]] = true
+	}
+
+	weighted := make([]Person, len(people))
+	copy(weighted, people)
+	for row, record := range records[1:] {
+		line := row + 2
+		if len(record) != len(names) {
+			return nil, fmt.Errorf("line %d: matrix must be square, but has %d columns for %d people", line, len(record), len(names))
+		}
+		person := &weighted[ids[names[row]]]
+		person.Preferences = append([]Preference(nil), person.Preferences...)
+		for column, cell := range record {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(cell), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: weight for %s not understood: %w", line, names[column], err)
+			}
+			if column != row && weight != 0 {
+				person.Preferences = append(person.Preferences, Preference{Name: names[column], Weight: weight})
+			}
+		}
+	}
+	return weighted, nil
+}
+
+// Config holds the parameters used to solve a problem
+type Config struct {
+	AnnealConfig
+	Mode         string       // the cost function to maximise: sum, count, hybrid or maximin
+	CostFunction CostFunction // if given, maximised in place of the mode's cost function
+	ObjectiveWeights
+	MinSatisfiedPerPerson int     // solutions where someone has fewer of their preferences are heavily penalised
+	Balance               string  // if given, the attribute (see Person.Attributes) whose mix at each table is kept close to everyone's, with BalanceWeight
+	Penalty               float64 // the cost taken off for each hard constraint broken (0 picks one that no preferences can make up for)
+	Seed                  int64   // the seed for the random number generators, so that runs can be repeated
+	Restarts              int     // the number of independent runs to take the best of, with seeds counting up from Seed
+	Solver                Solver
+	TopK                  int     // if more than 1, the number of distinct solutions to give (see Solution.Top)
+	TopKDistinct          float64 // the fraction of people who must be sat at a different table (matching up interchangeable tables) for solutions to be distinct
+}
+
+// ObjectiveWeights are the weights of the soft terms the cost is made up of, in one place so that they can be tuned
+// together. A weight of 0 turns its term off, apart from the preferences themselves, so the zero value only counts
+// preferences (each by its own weight)
+type ObjectiveWeights struct {
+	PreferenceWeight    float64 // each satisfied preference's weight is multiplied by this (0 is taken as 1)
+	AdjacentTableCredit float64 // the credit given for a preference sat at an adjacent table
+	AvoidPenalty        float64 // the cost taken off for each person sat with someone they want to avoid
+	LonelyPenalty       float64 // the cost taken off for each person sat with none of their preferences
+	MetPenalty          float64 // the cost taken off for each time a pair sat together have been sat together before, when solving rounds
+	MutualBonus         float64 // the extra cost given for each pair sat together who both prefer each other
+	DesirabilityWeight  float64 // the extra cost given for each person's VIP score times the desirability of their table
+	StabilityWeight     float64 // the cost taken off for each person sat at a different table from the previous assignment
+	BalanceWeight       float64 // the cost taken off for each person a table is away from everyone's mix of Config.Balance
+}
+
+// ObjectiveTerms are the names of the weights in ObjectiveWeights, as taken by ObjectiveWeights.Set
+var ObjectiveTerms = []string{"preferences", "adjacent", "avoid", "lonely", "met", "mutual", "desirability", "stability", "balance"}
+
+// Set sets the weight of the named term (one of ObjectiveTerms)
+func (w *ObjectiveWeights) Set(term string, weight float64) error {
+	switch term {
+	case "preferences":
+		w.PreferenceWeight = weight
+	case "adjacent":
+		w.AdjacentTableCredit = weight
+	case "avoid":
+		w.AvoidPenalty = weight
+	case "lonely":
+		w.LonelyPenalty = weight
+	case "met":
+		w.MetPenalty = weight
+	case "mutual":
+		w.MutualBonus = weight
+	case "desirability":
+		w.DesirabilityWeight = weight
+	case "stability":
+		w.StabilityWeight = weight
+	case "balance":
+		w.BalanceWeight = weight
+	default:
+		return fmt.Errorf("objective term '%s' not understood, as it isn't one of %s", term, strings.Join(ObjectiveTerms, ", "))
+	}
+	return nil
+}
+
+// Solver is how a problem is solved
+type Solver int
+
+const (
+	AnnealSolver  Solver = iota // simulated annealing, with the annealing parameters
+	GreedySolver                // people are sat one at a time at the table that most improves the cost, without annealing
+	OptimalSolver               // every way of seating people is tried, which is only practical for a handful of people
+)
+
+// CoolingSchedule is how the temperature is lowered at each step
+type CoolingSchedule int
+
+const (
+	Geometric CoolingSchedule = iota // the temperature is multiplied by the cooling rate
+	Linear                           // the temperature is lowered by the same amount, over the given number of steps
+)
+
+// SwapSchedule is how the number of swaps made to get a neighbouring solution changes as the annealers cool
+type SwapSchedule int
+
+const (
+	FixedSwaps      SwapSchedule = iota // always the swap count
+	DecreasingSwaps                     // the swap count at the base temperature, falling to 1 at the final temperature
+)
+
+// ReplicaExchange is how the annealers decide whether to swap solutions with the next coldest one after each step
+type ReplicaExchange int
+
+const (
+	Deterministic ReplicaExchange = iota // swap only when the hotter annealer has the better solution
+	Metropolis                           // also swap a worse solution down, with a chance based on the costs and temperatures
+)
+
+// Initialisation is how the starting solution is chosen
+type Initialisation int
+
+const (
+	RandomInit    Initialisation = iota // everyone is sat in a random seat
+	GreedyInit                          // people are sat one at a time at the table that most improves the cost
+	ClusteredInit                       // clusters of people who prefer each other are sat together, the largest first
+)
+
+// AnnealConfig holds the parameters of the annealing process
+type AnnealConfig struct {
+	Initialisation      Initialisation
+	BaseTemperature     float64 // the temperature the coldest annealer starts at
+	TargetAcceptance    float64 // if given, the base temperature is instead estimated so that about this fraction of moves are accepted at first
+	FinalTemperature    float64 // annealing stops once the coldest annealer has cooled to this
+	CoolingSchedule     CoolingSchedule
+	CoolingRate         float64 // the temperature is multiplied by this at each step, when cooling geometrically
+	AdaptiveCooling     bool    // cool more slowly (never more quickly than the cooling rate) while the fraction of moves accepted drops quickly
+	CoolingSteps        int     // the number of steps taken to cool to the final temperature, when cooling linearly
+	InternalIterations  int     // the number of neighbouring solutions tried at each step
+	IterationsPerPerson int     // if given, the internal iterations are instead this many for each person, to scale with the problem
+	SwapCount           int     // the number of swaps made to get a neighbouring solution
+	SwapSchedule        SwapSchedule
+	NeighbourMix        float64 // the chance that each swap moves someone into an empty seat, rather than swapping any two seats
+	ConcurrentAnnealers int     // the number of annealers, each twice as hot as the last
+	CandidateWorkers    int     // if more than 1, each annealer makes this many neighbours at once at each iteration, only considering the best
+	Exchange            ReplicaExchange
+	StallLimit          int           // stop early if the best cost hasn't improved for this many steps (0 never stops early)
+	ReheatAfterStall    int           // raise the temperature if the best cost hasn't improved for this many steps (0 never does)
+	ReheatFactor        float64       // the temperature is multiplied by this when reheating, up to the base temperature
+	MaxReheats          int           // the most times the temperature is raised, so that runs still finish
+	Polish              bool          // after cooling, keep making any swap that improves the best solution until none do
+	MaxTime             time.Duration // if given, keep annealing (from the base temperature again each time it cools) until this long has passed
+
+	Progress   func(Progress)   // if given, called after each temperature step
+	Checkpoint func(Checkpoint) // if given, called with the best solution so far every few temperature steps and at the end
+}
+
+// Iterations returns the number of neighbouring solutions tried at each step for a problem with the given number of
+// people, which is the internal iterations unless they're given per person
+func (cfg AnnealConfig) Iterations(people int) int {
+	if cfg.IterationsPerPerson > 0 {
+		return cfg.IterationsPerPerson * people
+	}
+	return cfg.InternalIterations
+}
+
+// Validate returns an error if the annealing parameters would not give a sensible (or finite) run
+func (cfg AnnealConfig) Validate() error {
+	if cfg.Initialisation != RandomInit && cfg.Initialisation != GreedyInit && cfg.Initialisation != ClusteredInit {
+		return fmt.Errorf("initialisation %d not understood", cfg.Initialisation)
+	}
+	switch cfg.CoolingSchedule {
+	case Geometric:
+		if cfg.CoolingRate <= 0 || cfg.CoolingRate >= 1 {
+			return fmt.Errorf("cooling rate must be greater than 0 and less than 1, but is %g", cfg.CoolingRate)
+		}
+	case Linear:
+		if cfg.CoolingSteps <= 0 {
+			return fmt.Errorf("cooling steps must be positive, but is %d", cfg.CoolingSteps)
+		}
+	default:
+		return fmt.Errorf("cooling schedule %d not understood", cfg.CoolingSchedule)
+	}
+	if cfg.AdaptiveCooling && cfg.CoolingSchedule != Geometric {
+		return fmt.Errorf("adaptive cooling needs geometric cooling")
+	}
+	if cfg.FinalTemperature <= 0 {
+		return fmt.Errorf("final temperature must be greater than 0, but is %g", cfg.FinalTemperature)
+	}
+	if cfg.BaseTemperature <= cfg.FinalTemperature {
+		return fmt.Errorf("base temperature %g must be greater than the final temperature %g", cfg.BaseTemperature, cfg.FinalTemperature)
+	}
+	if cfg.TargetAcceptance < 0 || cfg.TargetAcceptance >= 1 {
+		return fmt.Errorf("target acceptance must be at least 0 and less than 1, but is %g", cfg.TargetAcceptance)
+	}
+	if cfg.IterationsPerPerson < 0 {
+		return fmt.Errorf("iterations per person must not be negative, but is %d", cfg.IterationsPerPerson)
+	}
+	if cfg.InternalIterations <= 0 && cfg.IterationsPerPerson == 0 {
+		return fmt.Errorf("internal iterations must be positive, but is %d", cfg.InternalIterations)
+	}
+	if cfg.SwapCount <= 0 {
+		return fmt.Errorf("swap count must be positive, but is %d", cfg.SwapCount)
+	}
+	if cfg.SwapSchedule != FixedSwaps && cfg.SwapSchedule != DecreasingSwaps {
+		return fmt.Errorf("swap schedule %d not understood", cfg.SwapSchedule)
+	}
+	if cfg.NeighbourMix < 0 || cfg.NeighbourMix > 1 {
+		return fmt.Errorf("neighbour mix must be between 0 and 1, but is %g", cfg.NeighbourMix)
+	}
+	if cfg.ConcurrentAnnealers <= 0 {
+		return fmt.Errorf("concurrent annealers must be positive, but is %d", cfg.ConcurrentAnnealers)
+	}
+	if cfg.CandidateWorkers < 0 {
+		return fmt.Errorf("candidate workers must not be negative, but is %d", cfg.CandidateWorkers)
+	}
+	if cfg.Exchange != Deterministic && cfg.Exchange != Metropolis {
+		return fmt.Errorf("replica exchange %d not understood", cfg.Exchange)
+	}
+	if cfg.StallLimit < 0 {
+		return fmt.Errorf("stall limit must not be negative, but is %d", cfg.StallLimit)
+	}
+	if cfg.ReheatAfterStall < 0 {
+		return fmt.Errorf("steps before reheating must not be negative, but is %d", cfg.ReheatAfterStall)
+	}
+	if cfg.MaxReheats < 0 {
+		return fmt.Errorf("maximum reheats must not be negative, but is %d", cfg.MaxReheats)
+	}
+	if cfg.MaxTime < 0 {
+		return fmt.Errorf("max time must not be negative, but is %s", cfg.MaxTime)
+	}
+	if cfg.ReheatAfterStall > 0 && cfg.MaxReheats > 0 && cfg.ReheatFactor <= 1 {
+		return fmt.Errorf("reheat factor must be greater than 1, but is %g", cfg.ReheatFactor)
+	}
+	return nil
+}
+
+// Solution is the best assignment of people to tables that was found, along with its cost
+type Solution struct {
+	Assignment   []table
+	Cost         float64
+	Satisfaction float64 // the percentage of preferences (by weight) sat at the same table
+	Stats        Stats
+	Top          []Solution // with Config.TopK, the best distinct solutions found (without their stats), best first
+}
+
+// Tables returns the names of the people sat at each table in the order they're given in the problem, leaving out
+// empty seats
+func (s Solution) Tables() [][]string {
+	names := make([][]string, len(s.Assignment))
+	for i, table := range s.Assignment {
+		names[i] = []string{}
+		for _, person := range inInputOrder(table) {
+			names[i] = append(names[i], person.Name)
+		}
+	}
+	return names
+}
+
+// inInputOrder returns the people sat at the table in the order they're given in the problem (rather than the order of
+// the seats, which annealing shuffles), leaving out empty seats, so that the same seating is always printed the same
+func inInputOrder(t table) []Person {
+	people := seatedAt(t)
+	sort.Slice(people, func(i, j int) bool {
+		return people[i].id < people[j].id
+	})
+	return people
+}
+
+// Checkpoint is the state of a run part of the way through, so that it can be carried on from later by solving with
+// its assignment (see Problem) as the starting solution and its temperature as the base temperature
+type Checkpoint struct {
+	Seed        int64      `json:"seed"` // the seed of the run the checkpoint is from
+	Step        int        `json:"step"`
+	Temperature float64    `json:"temperature"` // the temperature of the coldest annealer at the next step
+	Cost        float64    `json:"cost"`
+	Assignment  [][]string `json:"assignment"` // the names sat at each table in the best solution so far
+}
+
+// MarshalJSON gives the solution as its tables, in order, with the names sat at each and the solution's cost and
+// satisfaction
+func (s Solution) MarshalJSON() ([]byte, error) {
+	type tableJSON struct {
+		Index    int      `json:"index"`
+		Name     string   `json:"name"`
+		Capacity int      `json:"capacity"`
+		Minimum  int      `json:"minimum,omitempty"`
+		People   []string `json:"people"`
+	}
+	solution := struct {
+		Tables          []tableJSON `json:"tables"`
+		Cost            float64     `json:"cost"`
+		SatisfactionPct float64     `json:"satisfactionPct"`
+	}{Tables: make([]tableJSON, len(s.Assignment)), Cost: s.Cost, SatisfactionPct: s.Satisfaction}
+
+	for i, names := range s.Tables() {
+		solution.Tables[i] = tableJSON{Index: i, Name: s.Assignment[i].name, Capacity: s.Assignment[i].capacity, Minimum: s.Assignment[i].minimum, People: names}
+	}
+	return json.Marshal(solution)
+}
+
+// Solve anneals the problem with the given configuration, returning the best solution found. If the context is
+// cancelled or times out, the best solution found so far is returned along with the context's error
+func Solve(ctx context.Context, p Problem, cfg Config) (Solution, error) {
+	p, err := expandProblem(p)
+	if err != nil {
+		return Solution{}, problemError{err}
+	}
+	return solve(ctx, p, cfg, nil)
+}
+
+// SolveRounds solves the problem for the given number of rounds, as when people move tables between courses. Each pair
+// sat together again after an earlier round has cfg.MetPenalty taken off for each time they've already been sat
+// together, so that people meet someone new. The seeds for each round carry on from the last round's restarts. If the
+// context is cancelled, the rounds solved so far are returned (with the best solution found for the round it was
+// cancelled in) along with the context's error
+func SolveRounds(ctx context.Context, p Problem, cfg Config, rounds int) ([]Solution, error) {
+	if rounds <= 0 {
+		return nil, fmt.Errorf("rounds must be positive, but is %d", rounds)
+	}
+	p, err := expandProblem(p)
+	if err != nil {
+		return nil, problemError{err}
+	}
+	runs := cfg.Restarts
+	if runs < 1 {
+		runs = 1
+	}
+
+	// how many times each pair has been sat together, by index
+	met := make([][]int, len(p.People))
+	for i := range met {
+		met[i] = make([]int, len(p.People))
+	}
+
+	var solutions []Solution
+	for round := 0; round < rounds; round++ {
+		roundCfg := cfg
+		roundCfg.Seed = cfg.Seed + int64(round*runs)
+		solution, err := solve(ctx, p, roundCfg, met)
+		if solution.Assignment != nil {
+			solutions = append(solutions, solution)
+		}
+		if err != nil {
+			return solutions, err
+		}
+		for _, table := range solution.Assignment {
+			for _, person := range table.people {
+				for _, other := range table.people {
+					if !person.empty && !other.empty && person.id != other.id {
+						met[person.id][other.id]++
+					}
+				}
+			}
+		}
+	}
+	return solutions, nil
+}
+
+// problemError is an error in the problem being solved (which can't be solved as it stands), rather than in how it was
+// asked to be solved
+type problemError struct {
+	err error
+}
+
+func (e problemError) Error() string {
+	return e.err.Error()
+}
+
+func (e problemError) Unwrap() error {
+	return e.err
+}
+
+// solve anneals the problem, where met is how many times each pair has already been sat together (if there have been
+// earlier rounds)
+func solve(ctx context.Context, p Problem, cfg Config, met [][]int) (Solution, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return Solution{}, err
+	}
+	cfg.InternalIterations = cfg.Iterations(len(p.People))
+	o, err := objectiveFor(cfg.Mode)
+	if cfg.CostFunction != nil {
+		o, err = objectiveOf(cfg.CostFunction), nil
+	}
+	if err != nil {
+		return Solution{}, err
+	}
+	tables, err := newTables(p)
+	if err != nil {
+		return Solution{}, problemError{err}
+	}
+	s, err := newScoring(p, tables, cfg, met)
+	if err != nil {
+		return Solution{}, problemError{err}
+	}
+
+	p.People = indexPeople(p.People)
+	unpinned, err := pinPeople(p, tables)
+	if err != nil {
+		return Solution{}, problemError{err}
+	}
+
+	if cfg.Restarts < 0 {
+		return Solution{}, fmt.Errorf("restarts must not be negative, but is %d", cfg.Restarts)
+	}
+	if cfg.TopK < 0 {
+		return Solution{}, fmt.Errorf("top k must not be negative, but is %d", cfg.TopK)
+	}
+	if cfg.TopKDistinct < 0 || cfg.TopKDistinct > 1 {
+		return Solution{}, fmt.Errorf("top k distinct fraction must be between 0 and 1, but is %g", cfg.TopKDistinct)
+	}
+	var pool *solutionPool
+	if cfg.TopK > 1 {
+		pool = newSolutionPool(cfg.TopK, cfg.TopKDistinct)
+	}
+	run := anneal
+	switch cfg.Solver {
+	case AnnealSolver:
+	case GreedySolver:
+		run = greedy
+		if p.Assignment != nil {
+			return Solution{}, problemError{fmt.Errorf("the greedy solver can't start from an assignment")}
+		}
+	case OptimalSolver:
+		run = optimal
+		if p.Assignment != nil {
+			return Solution{}, problemError{fmt.Errorf("the optimal solver can't start from an assignment")}
+		}
+		if len(unpinned) > optimalMaxPeople {
+			return Solution{}, problemError{fmt.Errorf("the optimal solver tries every way of seating people, so can seat at most %d (besides anyone pinned), but there are %d", optimalMaxPeople, len(unpinned))}
+		}
+	default:
+		return Solution{}, fmt.Errorf("solver %d not understood", cfg.Solver)
+	}
+
+	// a warm start has to be a legal solution in its own right
+	var start []table
+	if p.Assignment != nil {
+		start, err = seatNames(p.Assignment, p.People, tables)
+		if err != nil {
+			return Solution{}, problemError{fmt.Errorf("assignment to start from: %w", err)}
+		}
+	}
+
+	// run at least once, keeping the best of the restarts (and stopping if the context is cancelled)
+	var assignment []table
+	var runStats Stats
+	var restartCosts []float64
+	var restartAssignments [][]table
+	var rediscovered []int
+	for restart := 0; restart == 0 || restart < cfg.Restarts; restart++ {
+		// the solver fills in the tables it's given, so each restart starts from its own copy
+		restartAssignment, restartStats, restartErr := run(ctx, cfg.Seed+int64(restart), unpinned, copyAssignment(tables), start, s, o, cfg.AnnealConfig, pool)
+		restartCosts = append(restartCosts, restartStats.FinalCost)
+		for _, earlier := range restartAssignments {
+			if solutionSimilarity(restartAssignment, earlier) == 1 {
+				rediscovered = append(rediscovered, restart)
+				break
+			}
+		}
+		restartAssignments = append(restartAssignments, restartAssignment)
+		if assignment == nil || restartStats.FinalCost > runStats.FinalCost {
+			assignment, runStats = restartAssignment, restartStats
+			runStats.Restart = restart
+		}
+		err = restartErr
+		if err != nil {
+			break
+		}
+	}
+	runStats.MaxPossibleCost = o.maxPossible(assignment, s)
+	runStats.RestartCosts = restartCosts
+	runStats.Rediscovered = rediscovered
+
+	return Solution{Assignment: assignment, Cost: runStats.FinalCost, Satisfaction: getSatisfaction(assignment), Stats: runStats, Top: pool.solutions()}, err
+}
+
+// newTables converts the problem's table capacities into a slice of empty table structs, checking that there is
+// a seat for everyone
+func newTables(p Problem) ([]table, error) {
+	tables := make([]table, len(p.Tables))
+
+	seats, minimumSeats := 0, 0
+	for i, spec := range p.Tables {
+		if spec.Max <= 0 {
+			return nil, fmt.Errorf("table %d must have a positive capacity, but has a capacity of %d", i, spec.Max)
+		}
+		if spec.Min < 0 || spec.Min > spec.Max {
+			return nil, fmt.Errorf("table %d must seat at least %d and at most %d people, which isn't possible", i, spec.Min, spec.Max)
+		}
+		tables[i].name = spec.Name
+		tables[i].anchored = spec.Name != ""
+		if tables[i].name == "" {
+			tables[i].name = fmt.Sprintf("Table %d", i)
+		}
+		tables[i].capacity = spec.Max
+		tables[i].minimum = spec.Min
+		tables[i].desirability = spec.Desirability
+		tables[i].people = make([]Person, tables[i].capacity)
+		tables[i].seated = make([]bool, len(p.People))
+		tables[i].tagged = make([]int, len(tagIDs(p.People)))
+		seats += tables[i].capacity
+		minimumSeats += tables[i].minimum
+	}
+	if len(p.Tables) == 0 {
+		return nil, fmt.Errorf("there are no tables to seat people at")
+	}
+	if len(p.People) == 0 {
+		return nil, fmt.Errorf("there are no people to seat")
+	}
+	if seats < len(p.People) {
+		return nil, fmt.Errorf("tables seat %d but there are %d people", seats, len(p.People))
+	}
+	if minimumSeats > len(p.People) {
+		return nil, fmt.Errorf("tables need at least %d people but there are %d", minimumSeats, len(p.People))
+	}
+
+	// record which tables are next to each other, in both directions
+	for _, pair := range p.AdjacentTables {
+		for _, index := range pair {
+			if index < 0 || index >= len(tables) {
+				return nil, fmt.Errorf("adjacent table index %d out of range", index)
+			}
+		}
+		tables[pair[0]].adjacent = append(tables[pair[0]].adjacent, pair[1])
+		tables[pair[1]].adjacent = append(tables[pair[1]].adjacent, pair[0])
+	}
+	return tables, nil
+}
+
+// pinPeople sits each pinned person at the front of their table, returning everyone else for the annealer to seat
+func pinPeople(p Problem, tables []table) (unpinned []Person, err error) {
+	for _, person := range p.People {
+		tableNo, pinned := p.Pinned[person.Name]
+		if !pinned {
+			unpinned = append(unpinned, person)
+			continue
+		}
+		if tableNo < 0 || tableNo >= len(tables) {
+			return nil, fmt.Errorf("%s is pinned to table %d, which does not exist", person.Name, tableNo)
+		}
+		if tables[tableNo].pinned == tables[tableNo].capacity {
+			return nil, fmt.Errorf("more people are pinned to table %d than it seats", tableNo)
+		}
+		tables[tableNo].people[tables[tableNo].pinned] = person
+		tables[tableNo].seat(person)
+		tables[tableNo].pinned++
+	}
+	if len(p.Pinned) != len(p.People)-len(unpinned) {
+		return nil, fmt.Errorf("%d people are pinned but not all of them are in the problem", len(p.Pinned))
+	}
+
+	// only the people who aren't pinned can be moved to make up a table's minimum
+	shortfall := 0
+	for _, table := range tables {
+		if table.minimum > table.pinned {
+			shortfall += table.minimum - table.pinned
+		}
+	}
+	if shortfall > len(unpinned) {
+		return nil, fmt.Errorf("the tables' minimums need %d more people than are pinned to them, but only %d people aren't pinned", shortfall, len(unpinned))
+	}
+
+	// the annealer needs two tables to swap between, and someone to swap
+	if len(movableTables(tables)) < 2 {
+		return nil, fmt.Errorf("at least two tables need seats that nobody is pinned to")
+	}
+	if len(unpinned) == 0 {
+		return nil, fmt.Errorf("everyone is pinned, so there is nobody to seat")
+	}
+	return unpinned, nil
+}
+
+// unknownNames describes each preference or avoid entry that doesn't name anyone in the problem, as these can never be
+// satisfied (and are most likely misspelt)
+func unknownNames(p Problem) (unknown []string) {
+	names := make(map[string]bool)
+	for _, person := range p.People {
+		names[person.Name] = true
+	}
+	tags := tagIDs(p.People)
+	for _, person := range p.People {
+		for _, preference := range person.Preferences {
+			if strings.HasPrefix(preference.Name, "#") {
+				if _, exists := tags[strings.TrimPrefix(preference.Name, "#")]; !exists {
+					unknown = append(unknown, fmt.Sprintf("%s prefers '%s' but nobody has that tag", person.Name, preference.Name))
+				}
+			} else if !names[preference.Name] {
+				unknown = append(unknown, fmt.Sprintf("%s prefers '%s' but no such guest exists", person.Name, preference.Name))
+			}
+		}
+		for _, name := range person.Avoid {
+			if !names[name] {
+				unknown = append(unknown, fmt.Sprintf("%s avoids '%s' but no such guest exists", person.Name, name))
+			}
+		}
+	}
+	for _, pair := range p.Apart {
+		for _, name := range pair {
+			if !names[name] {
+				unknown = append(unknown, fmt.Sprintf("'%s' is to be kept apart but no such guest exists", name))
+			}
+		}
+	}
+	for _, group := range p.Groups {
+		for _, name := range group {
+			if !names[name] {
+				unknown = append(unknown, fmt.Sprintf("'%s' is in a group but no such guest exists", name))
+			}
+		}
+	}
+	for _, table := range p.Previous {
+		for _, name := range table {
+			if !names[name] {
+				unknown = append(unknown, fmt.Sprintf("'%s' is in the previous assignment but no such guest exists", name))
+			}
+		}
+	}
+	for name := range p.Forbid {
+		if !names[name] {
+			unknown = append(unknown, fmt.Sprintf("'%s' is forbidden from some tables but no such guest exists", name))
+		}
+	}
+	return unknown
+}
+
+// selfReferences describes each preference or avoid entry naming the person themselves, which are left out of the
+// scoring as everyone is always sat with themselves
+func selfReferences(p Problem) (self []string) {
+	for _, person := range p.People {
+		for _, preference := range person.Preferences {
+			if preference.Name == person.Name {
+				self = append(self, fmt.Sprintf("%s prefers themselves, which is ignored", person.Name))
+			}
+		}
+		for _, name := range person.Avoid {
+			if name == person.Name {
+				self = append(self, fmt.Sprintf("%s avoids themselves, which is ignored", person.Name))
+			}
+		}
+	}
+	return self
+}
+
+// newScoring gathers what the cost functions need from the problem and configuration, along with how many times each
+// pair has already been sat together (which can be nil)
+func newScoring(p Problem, tables []table, cfg Config, met [][]int) (scoring, error) {
+	err := checkMinSatisfied(p.People, tables, cfg.MinSatisfiedPerPerson)
+	if err != nil {
+		return scoring{}, fmt.Errorf("infeasible minimum number of satisfied preferences: %w", err)
+	}
+
+	// parse through the plus-ones, where anyone who isn't in the problem can never be sat with
+	ids := personIDs(p.People)
+	plusOnes := make(map[int]int)
+	for _, plusOne := range p.PlusOnes {
+		if id, exists := ids[plusOne.PersonOne]; exists {
+			plusOnes[id] = lookupID(ids, plusOne.PersonTwo)
+		}
+	}
+
+	// pairs kept apart are looked up from both people, where anyone who isn't in the problem is never sat with
+	apart := make(map[int][]int)
+	for _, pair := range p.Apart {
+		one, two := lookupID(ids, pair[0]), lookupID(ids, pair[1])
+		if one >= 0 && two >= 0 {
+			apart[one] = append(apart[one], two)
+			apart[two] = append(apart[two], one)
+		}
+	}
+
+	// everyone in a group is looked up from the rest of it, so the group has to fit at a table
+	largest := 0
+	for _, table := range tables {
+		if table.capacity > largest {
+			largest = table.capacity
+		}
+	}
+	together := make(map[int][]int)
+	for _, group := range p.Groups {
+		if len(group) > largest {
+			return scoring{}, fmt.Errorf("a group of %d people is larger than the largest table, which seats %d", len(group), largest)
+		}
+		for _, name := range group {
+			one := lookupID(ids, name)
+			for _, other := range group {
+				if two := lookupID(ids, other); one >= 0 && two >= 0 && one != two {
+					together[one] = append(together[one], two)
+				}
+			}
+		}
+	}
+
+	// tables people are forbidden from are looked up by the person, so nobody can be forbidden from every table (or
+	// pinned to one they're forbidden from)
+	forbidden := make(map[int][]int)
+	for name, tableNos := range p.Forbid {
+		for _, tableNo := range tableNos {
+			if tableNo < 0 || tableNo >= len(tables) {
+				return scoring{}, fmt.Errorf("%s is forbidden from table %d, which does not exist", name, tableNo)
+			}
+			if pinnedNo, pinned := p.Pinned[name]; pinned && pinnedNo == tableNo {
+				return scoring{}, fmt.Errorf("%s is pinned to table %d, which they're forbidden from", name, tableNo)
+			}
+			tables[tableNo].anchored = true
+		}
+		id := lookupID(ids, name)
+		if id < 0 {
+			continue
+		}
+		forbidden[id] = tableNos
+		allowed := 0
+		for tableNo := range tables {
+			if !isForbidden(forbidden, id, tableNo) {
+				allowed++
+			}
+		}
+		if allowed == 0 {
+			return scoring{}, fmt.Errorf("%s is forbidden from every table", name)
+		}
+	}
+
+	// where people were sat before, where anyone who isn't in the problem is ignored
+	previous := make(map[int]int)
+	for tableNo, names := range p.Previous {
+		if tableNo < len(tables) {
+			tables[tableNo].anchored = true
+		}
+		for _, name := range names {
+			if id := lookupID(ids, name); id >= 0 {
+				previous[id] = tableNo
+			}
+		}
+	}
+
+	if cfg.Penalty < 0 {
+		return scoring{}, fmt.Errorf("penalty must not be negative, but is %g", cfg.Penalty)
+	}
+	s := scoring{plusOnes: plusOnes, preferences: cfg.PreferenceWeight, adjacentCredit: cfg.AdjacentTableCredit, minSatisfied: cfg.MinSatisfiedPerPerson, avoidPenalty: cfg.AvoidPenalty, lonelyPenalty: cfg.LonelyPenalty, met: met, metPenalty: cfg.MetPenalty, mutualBonus: cfg.MutualBonus, desirability: cfg.DesirabilityWeight, mutual: getMutual(indexPeople(p.People)), apart: apart, together: together, forbidden: forbidden, previous: previous, stability: cfg.StabilityWeight, penalty: cfg.Penalty}
+
+	if cfg.Balance != "" && cfg.BalanceWeight != 0 {
+		s.balanced, s.shares, err = attributeShares(p.People, cfg.Balance)
+		if err != nil {
+			return scoring{}, err
+		}
+		s.balance = cfg.BalanceWeight
+	}
+
+	// under maximin, each person fewer who is worst off is worth more than any sum (and each preference more for them
+	// is worth more than everyone being worst off), and the default penalty has to be worth more again
+	s.worstOffScale = softRange(indexPeople(p.People), tables, s) + 1
+	if s.penalty == 0 {
+		s.penalty = s.worstOffScale
+		if cfg.Mode == "maximin" {
+			s.penalty *= float64(mostPreferences(p.People)+1) * float64(len(p.People)+1)
+		}
+	}
+	return s, nil
+}
+
+// mostPreferences returns the most preferences anyone has
+func mostPreferences(people []Person) (most int) {
+	for _, person := range people {
+		if wanted(person) > most {
+			most = wanted(person)
+		}
+	}
+	return most
+}
+
+// softRange returns the most the preferences could ever change the cost by - the gap between everyone being sat with
+// everyone they prefer and everyone being lonely and sat with everyone they want to avoid (and has met before), along
+// with the most the VIPs' tables could change it by. The default penalty for each hard constraint broken is more than
+// this, so that a solution breaking them never beats one that doesn't
+func softRange(people []Person, tables []table, s scoring) float64 {
+	everyone := table{people: people, seated: make([]bool, len(people)), tagged: make([]int, len(tagIDs(people)))}
+	avoided := 0
+	for _, person := range people {
+		everyone.seat(person)
+		avoided += len(person.avoidIDs)
+	}
+	met := 0
+	for _, times := range s.met {
+		for _, n := range times {
+			met += n
+		}
+	}
+	vip, desirable := 0.0, 0.0
+	for _, person := range people {
+		vip += math.Abs(person.VIP)
+	}
+	for _, table := range tables {
+		desirable = math.Max(desirable, math.Abs(table.desirability))
+	}
+	highest := getHighestCost([]table{everyone}, s)
+	return highest + s.avoidPenalty*float64(avoided) + math.Abs(s.lonelyPenalty)*float64(len(people)) + s.metPenalty*float64(met/2) + math.Abs(s.desirability)*vip*desirable + math.Abs(s.stability)*float64(len(s.previous)) + 2*math.Abs(s.balance)*float64(len(people))
+}
+
+// attributeShares returns, for each person by index, the index of their value of the attribute (or -1 if they have
+// none), and the fraction of the people with the attribute who have each value
+func attributeShares(people []Person, attribute string) (balanced []int, shares []float64, err error) {
+	values := make(map[string]int)
+	balanced = make([]int, len(people))
+	counted := 0
+	for i, person := range people {
+		value, has := person.Attributes[attribute]
+		if !has {
+			balanced[i] = -1
+			continue
+		}
+		if _, seen := values[value]; !seen {
+			values[value] = len(shares)
+			shares = append(shares, 0)
+		}
+		balanced[i] = values[value]
+		shares[balanced[i]]++
+		counted++
+	}
+	if counted == 0 {
+		return nil, nil, fmt.Errorf("nobody has the attribute %s to balance the tables by", attribute)
+	}
+	for value := range shares {
+		shares[value] /= float64(counted)
+	}
+	return balanced, shares, nil
+}
+
+// indexPeople returns a copy of the people where everyone, and everyone (or every tag) they prefer or avoid, is given
+// their index (or -1 if there's nobody with that name), so that scoring doesn't need to look up names
+func indexPeople(people []Person) []Person {
+	ids := personIDs(people)
+	tags := tagIDs(people)
+	indexed := make([]Person, len(people))
+	for i, person := range people {
+		person.id = i
+		preferences := make([]Preference, 0, len(person.Preferences))
+		for _, preference := range person.Preferences {
+			if strings.HasPrefix(preference.Name, "#") {
+				preference.isTag = true
+				preference.id = lookupID(tags, strings.TrimPrefix(preference.Name, "#"))
+			} else {
+				preference.id = lookupID(ids, preference.Name)
+			}
+			// everyone is always sat with themselves, so preferring themselves is left out (see selfReferences)
+			if preference.isTag || preference.id != i {
+				preferences = append(preferences, preference)
+			}
+		}
+		person.Preferences = preferences
+		person.tagIDs = make([]int, len(person.Tags))
+		for j, tag := range person.Tags {
+			person.tagIDs[j] = tags[tag]
+		}
+		person.avoidIDs = make([]int, len(person.Avoid))
+		for j, name := range person.Avoid {
+			if person.avoidIDs[j] = lookupID(ids, name); person.avoidIDs[j] == i {
+				person.avoidIDs[j] = -1
+			}
+		}
+		indexed[i] = person
+	}
+	return indexed
+}
+
+// personIDs maps each person's name to their index
+func personIDs(people []Person) map[string]int {
+	ids := make(map[string]int)
+	for i, person := range people {
+		ids[person.Name] = i
+	}
+	return ids
+}
+
+// tagIDs maps each tag anyone has to an index
+func tagIDs(people []Person) map[string]int {
+	ids := make(map[string]int)
+	for _, person := range people {
+		for _, tag := range person.Tags {
+			if _, exists := ids[tag]; !exists {
+				ids[tag] = len(ids)
+			}
+		}
+	}
+	return ids
+}
+
+// lookupID returns the index of the named person, or -1 if there's nobody with that name
+func lookupID(ids map[string]int, name string) int {
+	id, exists := ids[name]
+	if !exists {
+		return -1
+	}
+	return id
+}
@@ -0,0 +1,201 @@
+package allocations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func ExampleLoadProblemStrict() {
+	for _, input := range []string{
+		`{"people":[{"name":"A"}],"tables":[{"capacity":8,"desirabilty":2}]}`,
+		`{"people":[{"name":"A","preferences":[{"name":"B","wieght":5}]},{"name":"B"}],"tables":[8]}`,
+		`{"people":[{"name":"A","preferences":["B",{"name":"B","weight":5}]},{"name":"B"}],"tables":[8,{"capacity":8,"desirability":2}]}`,
+	} {
+		_, err := LoadProblemStrict(strings.NewReader(input))
+		fmt.Println(err)
+	}
+	// Output:
+	// table 0: json: unknown field "desirabilty"
+	// a preference of A: json: unknown field "wieght"
+	// <nil>
+}
+
+func Example_newTables() {
+	for _, tables := range []string{"[2,0]", "[2,-1]"} {
+		p, err := LoadProblem(strings.NewReader(`{"people":[{"name":"A"},{"name":"B"}],"tables":` + tables + `}`))
+		if err != nil {
+			panic(err)
+		}
+		_, err = Solve(context.Background(), p, benchmarkConfig)
+		fmt.Println(err)
+	}
+	// Output:
+	// table 1 must have a positive capacity, but has a capacity of 0
+	// table 1 must have a positive capacity, but has a capacity of -1
+}
+
+// Example_forbid solves with a few seeds, where the people forbidden from tables would otherwise like to be sat at them
+func Example_forbid() {
+	p, err := LoadProblem(strings.NewReader(`{
+		"people":[
+			{"name":"A","preferences":[{"name":"C","weight":5}]},
+			{"name":"B","preferences":[{"name":"D","weight":5}]},
+			{"name":"C"},{"name":"D"},{"name":"E"},{"name":"F"}
+		],
+		"tables":[2,2,2],
+		"pinned":{"C":0,"D":2},
+		"forbid":{"A":[0,1],"B":[2]}
+	}`))
+	if err != nil {
+		panic(err)
+	}
+	for seed := int64(1); seed <= 3; seed++ {
+		cfg := benchmarkConfig
+		cfg.Seed = seed
+		solution, err := Solve(context.Background(), p, cfg)
+		if err != nil {
+			panic(err)
+		}
+		for tableNo, names := range solution.Tables() {
+			for _, name := range names {
+				if name == "A" || name == "B" {
+					fmt.Printf("seed %d: %s at table %d", seed, name, tableNo)
+					fmt.Println()
+				}
+			}
+		}
+	}
+	// Output:
+	// seed 1: B at table 1
+	// seed 1: A at table 2
+	// seed 2: B at table 1
+	// seed 2: A at table 2
+	// seed 3: B at table 1
+	// seed 3: A at table 2
+}
+
+// Example_pinned solves with the bride and groom pinned to one table (with room for only one of the two people who'd
+// like to sit with both) and someone else to another, and prints where each pinned person ends up
+func Example_pinned() {
+	p, err := LoadProblem(strings.NewReader(`{
+		"people":[
+			{"name":"Bride","preferences":["E","F"]},
+			{"name":"Groom","preferences":["E","F"]},
+			{"name":"C"},{"name":"D"},
+			{"name":"E","preferences":["Bride","Groom"]},
+			{"name":"F","preferences":["Bride","Groom"]},
+			{"name":"G"},{"name":"H"},{"name":"I"}
+		],
+		"tables":[3,3,3],
+		"pinned":{"Bride":0,"Groom":0,"C":1}
+	}`))
+	if err != nil {
+		panic(err)
+	}
+	solution, err := Solve(context.Background(), p, benchmarkConfig)
+	if err != nil {
+		panic(err)
+	}
+	for tableNo, names := range solution.Tables() {
+		for _, name := range names {
+			if _, pinned := p.Pinned[name]; pinned {
+				fmt.Printf("%s: table %d", name, tableNo)
+				fmt.Println()
+			}
+		}
+	}
+	// Output:
+	// Bride: table 0
+	// Groom: table 0
+	// C: table 1
+}
+
+// ExampleSolve_fixed solves a problem made in code rather than read from JSON, with people fixed at a table, which are
+// sat there in every round without the problem passed in being changed
+func ExampleSolve_fixed() {
+	p := Problem{
+		People: []Person{{Name: "A", Preferences: []Preference{{Name: "B", Weight: 1}}}, {Name: "B"}, {Name: "C"}, {Name: "D"}},
+		Tables: []TableSpec{{Max: 3}, {Max: 3, Fixed: []string{"Host"}}},
+	}
+	solution, err := Solve(context.Background(), p, benchmarkConfig)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("solved:", solution.Tables())
+	solutions, err := SolveRounds(context.Background(), p, benchmarkConfig, 2)
+	if err != nil {
+		panic(err)
+	}
+	for round, solution := range solutions {
+		fmt.Printf("round %d: %v", round, solution.Tables())
+		fmt.Println()
+	}
+	fmt.Println("people passed in:", len(p.People), "fixed:", p.Tables[1].Fixed, "pinned:", p.Pinned)
+
+	p.Tables[1].Fixed = []string{"Host", "Guest", "Plus one", "Friend"}
+	_, err = Solve(context.Background(), p, benchmarkConfig)
+	fmt.Println(err)
+	// Output:
+	// solved: [[C D] [A B Host]]
+	// round 0: [[C D] [A B Host]]
+	// round 1: [[A B D] [C Host]]
+	// people passed in: 4 fixed: [Host] pinned: map[]
+	// table 1 has 4 people fixed at it, but only seats 3
+}
+
+// ExampleSolve_edges solves a problem made in code rather than read from JSON, where the only preferences are its
+// edges, without the problem passed in being changed
+func ExampleSolve_edges() {
+	p := Problem{
+		People: []Person{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}},
+		Tables: []TableSpec{{Max: 2}, {Max: 2}},
+		Edges:  []Edge{{Names: [2]string{"A", "C"}, Weight: 1}, {Names: [2]string{"B", "D"}, Weight: 1}},
+	}
+	solution, err := Solve(context.Background(), p, benchmarkConfig)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("solved:", solution.Tables(), "satisfaction:", getSatisfaction(solution.Assignment))
+	fmt.Println("edges passed in:", len(p.Edges), "preferences of A:", p.People[0].Preferences)
+
+	p.Edges = append(p.Edges, Edge{Names: [2]string{"A", "E"}, Weight: 1})
+	_, err = Solve(context.Background(), p, benchmarkConfig)
+	fmt.Println(err)
+	// Output:
+	// solved: [[B D] [A C]] satisfaction: 100
+	// edges passed in: 2 preferences of A: []
+	// edge 2 names "E", who isn't in the problem
+}
+
+// ExampleTableSpec_UnmarshalJSON reads tables given in each way, where only a bare number must be filled - so five
+// people can't be sat at two tables of three given as numbers, but can be at two given with just their max
+func ExampleTableSpec_UnmarshalJSON() {
+	for _, tables := range []string{`3`, `{"max":3}`, `{"capacity":3}`, `{"min":2,"max":3}`} {
+		var spec TableSpec
+		err := json.Unmarshal([]byte(tables), &spec)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s: min %d, max %d", tables, spec.Min, spec.Max)
+		fmt.Println()
+	}
+
+	for _, tables := range []string{`[3,3]`, `[{"max":3},{"max":3}]`} {
+		p, err := LoadProblem(strings.NewReader(`{"people":[{"name":"A"},{"name":"B"},{"name":"C"},{"name":"D"},{"name":"E"}],"tables":` + tables + `}`))
+		if err != nil {
+			panic(err)
+		}
+		_, err = Solve(context.Background(), p, benchmarkConfig)
+		fmt.Printf("%s: %v", tables, err)
+		fmt.Println()
+	}
+	// Output:
+	// 3: min 3, max 3
+	// {"max":3}: min 0, max 3
+	// {"capacity":3}: min 0, max 3
+	// {"min":2,"max":3}: min 2, max 3
+	// [3,3]: tables need at least 6 people but there are 5
+	// [{"max":3},{"max":3}]: <nil>
+}